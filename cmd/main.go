@@ -1,12 +1,16 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/Martin-Hayot/auction-server/configs"
+	"github.com/Martin-Hayot/auction-server/internal/bidding"
 	"github.com/Martin-Hayot/auction-server/internal/database"
+	"github.com/Martin-Hayot/auction-server/internal/handlers/api"
 	"github.com/Martin-Hayot/auction-server/internal/handlers/websocket"
 	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
@@ -19,7 +23,8 @@ var (
 	baseStyle = lipgloss.NewStyle().
 			BorderStyle(lipgloss.NormalBorder()).
 			BorderForeground(lipgloss.Color("240"))
-	db database.Service
+	db             database.Service
+	auctionHandler *websocket.AuctionHandler
 )
 
 type tickMsg time.Time
@@ -33,6 +38,7 @@ func tick() tea.Cmd {
 // Define the model for the Bubble Tea application
 type model struct {
 	table    table.Model
+	stats    string
 	quitting bool
 }
 
@@ -47,6 +53,7 @@ func newTable() model {
 		{Title: "HIGHEST BIDDER", Width: 20},
 		{Title: "WINNER ID", Width: 20},
 		{Title: "TIME LEFT", Width: 20},
+		{Title: "WATCHERS", Width: 20},
 	}
 
 	auctions, err := db.GetCurrentAuctions()
@@ -58,6 +65,7 @@ func newTable() model {
 				table.WithColumns(columns),
 				table.WithRows([]table.Row{}),
 			),
+			stats: formatStats(),
 		}
 	}
 
@@ -86,6 +94,7 @@ func newTable() model {
 			currentBidder,
 			winner,
 			timeLeftStr,
+			formatWatchers(auctionHandler.Watchers(auction.ID)),
 		}
 		rows = append(rows, row)
 	}
@@ -108,7 +117,7 @@ func newTable() model {
 		Bold(false)
 	t.SetStyles(s)
 
-	return model{table: t}
+	return model{table: t, stats: formatStats()}
 }
 
 func updateTableRows(t table.Model) table.Model {
@@ -142,6 +151,7 @@ func updateTableRows(t table.Model) table.Model {
 			currentBidder,
 			winner,
 			timeLeftStr,
+			formatWatchers(auctionHandler.Watchers(auction.ID)),
 		}
 		rows = append(rows, row)
 	}
@@ -150,6 +160,36 @@ func updateTableRows(t table.Model) table.Model {
 	return t
 }
 
+// formatWatchers shows the signed-in watchers of an auction, followed by the anonymous ones.
+func formatWatchers(presence websocket.Presence) string {
+	return fmt.Sprintf("%d (+%d anon)", presence.Authenticated, presence.Anonymous)
+}
+
+// formatStats summarizes the send queues of the connected clients and the database
+// transactions retried after a conflict.
+func formatStats() string {
+	clients, queued := 0, 0
+	for _, stats := range auctionHandler.QueueStats() {
+		clients++
+		queued += stats.Queued
+	}
+	health := db.Health()
+	return fmt.Sprintf("clients: %d • queued: %d • dropped: %d • tx retries: %s (%s exhausted)",
+		clients, queued, auctionHandler.DroppedMessages(), health["tx_retries"], health["tx_retry_exhausted"])
+}
+
+// healthHandler reports the database statistics, transaction retries included.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	stats := db.Health()
+	w.Header().Set("Content-Type", "application/json")
+	if stats["status"] != "up" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		log.Error("Error writing health response: ", err)
+	}
+}
+
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var (
 		cmd  tea.Cmd
@@ -158,6 +198,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tickMsg:
 		m.table = updateTableRows(m.table)
+		m.stats = formatStats()
 
 	case tea.KeyMsg:
 		switch msg.String() {
@@ -180,7 +221,7 @@ func (m model) View() string {
 		return "Bye!\n"
 	}
 
-	return baseStyle.Render(m.table.View()) + "\n" + helpStyle.Render("• q: exit\n")
+	return baseStyle.Render(m.table.View()) + "\n" + helpStyle.Render(m.stats+"\n• q: exit\n")
 
 }
 
@@ -220,13 +261,17 @@ func main() {
 	defer db.Close()
 
 	// Initialize WebSocket handler
-	auctionHandler := websocket.NewAuctionWebSocketHandler(db)
+	bids := bidding.NewService(db, cfg)
+	auctionHandler = websocket.NewAuctionWebSocketHandler(db, bids, cfg)
 
 	// Start periodic check for auctions
 	auctionHandler.StartPeriodicCheck()
 
 	// Setup routes
 	http.HandleFunc("/ws/auction", auctionHandler.HandleAuctions)
+	http.HandleFunc("/sse/auction", auctionHandler.HandleAuctionEvents)
+	http.HandleFunc("GET /health", healthHandler)
+	api.NewHandler(db, bids, auctionHandler).Register(http.DefaultServeMux)
 
 	if cfg.Server.Env == "prod" {
 		// Start server
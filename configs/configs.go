@@ -3,6 +3,7 @@ package configs
 import (
 	"os"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/joho/godotenv"
@@ -19,9 +20,22 @@ type Config struct {
 		DatabaseUrl string `mapstructure:"database_url"`
 	} `mapstructure:"database"`
 	WebSocket struct {
-		PingInterval   string `mapstructure:"ping_interval"`
-		MaxMessageSize int    `mapstructure:"max_message_size"`
+		PingInterval          string   `mapstructure:"ping_interval"`
+		PongTimeout           string   `mapstructure:"pong_timeout"`
+		WriteTimeout          string   `mapstructure:"write_timeout"`
+		MaxMessageSize        int      `mapstructure:"max_message_size"`
+		AllowedOrigins        []string `mapstructure:"allowed_origins"`
+		SendQueueSize         int      `mapstructure:"send_queue_size"`
+		SendQueuePolicy       string   `mapstructure:"send_queue_policy"`
+		MaxConnectionsPerUser int      `mapstructure:"max_connections_per_user"`
+		PresenceInterval      string   `mapstructure:"presence_interval"`
 	} `mapstructure:"websocket"`
+	Auction struct {
+		SoftCloseWindow string `mapstructure:"soft_close_window"`
+		Extension       string `mapstructure:"extension"`
+		MaxExtension    string `mapstructure:"max_extension"`
+		TickInterval    string `mapstructure:"tick_interval"`
+	} `mapstructure:"auction"`
 	Auth struct {
 		SecretKey string `mapstructure:"secret_key"`
 	} `mapstructure:"auth"`
@@ -83,3 +97,17 @@ func substituteEnvVarsInConfig() {
 		}
 	}
 }
+
+// Duration parses a duration setting such as "30s" or "2m".
+// It returns fallback when the setting is empty or invalid.
+func Duration(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warnf("Invalid duration %q, using %v", value, fallback)
+		return fallback
+	}
+	return d
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
 	"github.com/charmbracelet/log"
 
 	"github.com/lestrrat-go/jwx/v3/jwa"
@@ -70,8 +71,11 @@ func JweToJwt(encryptedToken string) (string, error) {
 	return string(signed), nil
 }
 
+// SessionCookie is the name of the cookie holding the Auth.js session token.
+const SessionCookie = "authjs.session-token"
+
 func ValidateTokenFromCookie(r *http.Request) (jwt.Token, error) {
-	cookie, err := r.Cookie("authjs.session-token")
+	cookie, err := r.Cookie(SessionCookie)
 	if err != nil {
 		return nil, errors.New(http.StatusUnauthorized, "missing session token cookie")
 	}
@@ -98,3 +102,33 @@ func ValidateTokenFromCookie(r *http.Request) (jwt.Token, error) {
 
 	return token, nil
 }
+
+// UserLookup finds users by email.
+type UserLookup interface {
+	GetUserByEmail(email string) (types.User, error)
+}
+
+// UserFromRequest validates the session token from the cookie and loads its user.
+func UserFromRequest(r *http.Request, users UserLookup) (types.User, *errors.AppError) {
+	// Validate the token from the cookie
+	token, err := ValidateTokenFromCookie(r)
+	if err != nil || token == nil {
+		log.Debug("Invalid token: ", err)
+		return types.User{}, errors.New(errors.ErrInvalidToken, "Unauthorized")
+	}
+
+	var email string
+	err = token.Get("email", &email)
+	if err != nil {
+		log.Error("Error retrieving email from token claims", err)
+		return types.User{}, errors.New(errors.ErrInvalidToken, "Unauthorized")
+	}
+
+	// Check if the user exists
+	user, err := users.GetUserByEmail(email)
+	if err != nil {
+		log.Error("User not found: ", err)
+		return types.User{}, errors.New(errors.ErrInvalidToken, "User not found")
+	}
+	return user, nil
+}
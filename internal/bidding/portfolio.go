@@ -0,0 +1,21 @@
+package bidding
+
+import (
+	"github.com/Martin-Hayot/auction-server/internal/lifecycle"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+)
+
+// Portfolio returns every auction a user has bid on, with the user's highest bid,
+// whether they hold the current bid, and how the auction turned out for them.
+func (s *Service) Portfolio(userID string) ([]types.ClientAuction, error) {
+	clientAuctions, err := s.db.GetAuctionsByClientId(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range clientAuctions {
+		auction := clientAuctions[i].Auction
+		clientAuctions[i].Winning = auction.CurrentBidderID != nil && *auction.CurrentBidderID == userID
+		clientAuctions[i].Outcome = lifecycle.BidderOutcome(auction, userID)
+	}
+	return clientAuctions, nil
+}
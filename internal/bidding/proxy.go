@@ -0,0 +1,60 @@
+package bidding
+
+import "github.com/Martin-Hayot/auction-server/pkg/types"
+
+// Placement is a bid to record as the outcome of resolving proxy bids.
+type Placement struct {
+	UserID string
+	Price  int
+	Auto   bool // Placed by the server on behalf of the bidder's maximum
+}
+
+// ResolveProxyBids plays a new bid against the stored maximum bids of the
+// other bidders and returns the bids to record, in order. The last placement
+// is the new leading bid.
+//
+// proxies must be sorted by maximum amount descending, then by the time the
+// maximum was set, so that ties go to the bidder who committed first.
+func ResolveProxyBids(increment int, bidderID string, amount int, proxies []types.ProxyBid) []Placement {
+	if increment <= 0 {
+		increment = 1
+	}
+
+	bidderMax := amount
+	bidderRank, rivalRank := -1, -1
+	var rival types.ProxyBid
+	for i, proxy := range proxies {
+		if proxy.UserID == bidderID {
+			if proxy.MaxAmount > bidderMax {
+				bidderMax = proxy.MaxAmount
+				bidderRank = i
+			}
+			continue
+		}
+		if rivalRank == -1 {
+			rival = proxy
+			rivalRank = i
+		}
+	}
+
+	// Nobody else has a maximum that can match the bid
+	if rivalRank == -1 || rival.MaxAmount < amount {
+		return []Placement{{UserID: bidderID, Price: amount}}
+	}
+
+	rivalHolds := rival.MaxAmount > bidderMax ||
+		(rival.MaxAmount == bidderMax && (bidderRank == -1 || rivalRank < bidderRank))
+
+	if rivalHolds {
+		return []Placement{
+			{UserID: bidderID, Price: bidderMax, Auto: bidderMax > amount},
+			{UserID: rival.UserID, Price: min(rival.MaxAmount, bidderMax+increment), Auto: true},
+		}
+	}
+
+	price := min(bidderMax, rival.MaxAmount+increment)
+	return []Placement{
+		{UserID: rival.UserID, Price: rival.MaxAmount, Auto: true},
+		{UserID: bidderID, Price: price, Auto: price > amount},
+	}
+}
@@ -0,0 +1,127 @@
+package bidding
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+)
+
+func TestResolveProxyBids(t *testing.T) {
+	tests := []struct {
+		name      string
+		increment int
+		amount    int
+		proxies   []types.ProxyBid // Sorted as GetProxyBidsTx returns them
+		want      []Placement
+	}{
+		{
+			name:      "no maximum bids",
+			increment: 10,
+			amount:    300,
+			want:      []Placement{{UserID: "bidder", Price: 300}},
+		},
+		{
+			name:      "rival maximum below the bid",
+			increment: 10,
+			amount:    300,
+			proxies:   []types.ProxyBid{{UserID: "rival", MaxAmount: 250}},
+			want:      []Placement{{UserID: "bidder", Price: 300}},
+		},
+		{
+			name:      "rival holds by the increment",
+			increment: 10,
+			amount:    300,
+			proxies:   []types.ProxyBid{{UserID: "rival", MaxAmount: 500}},
+			want: []Placement{
+				{UserID: "bidder", Price: 300},
+				{UserID: "rival", Price: 310, Auto: true},
+			},
+		},
+		{
+			name:      "rival holds up to its maximum",
+			increment: 10,
+			amount:    300,
+			proxies:   []types.ProxyBid{{UserID: "rival", MaxAmount: 305}},
+			want: []Placement{
+				{UserID: "bidder", Price: 300},
+				{UserID: "rival", Price: 305, Auto: true},
+			},
+		},
+		{
+			name:      "rival holds when the bid matches its maximum",
+			increment: 10,
+			amount:    500,
+			proxies:   []types.ProxyBid{{UserID: "rival", MaxAmount: 500}},
+			want: []Placement{
+				{UserID: "bidder", Price: 500},
+				{UserID: "rival", Price: 500, Auto: true},
+			},
+		},
+		{
+			name:      "tie goes to the rival who set the maximum first",
+			increment: 10,
+			amount:    300,
+			proxies: []types.ProxyBid{
+				{UserID: "rival", MaxAmount: 500},
+				{UserID: "bidder", MaxAmount: 500},
+			},
+			want: []Placement{
+				{UserID: "bidder", Price: 500, Auto: true},
+				{UserID: "rival", Price: 500, Auto: true},
+			},
+		},
+		{
+			name:      "tie goes to the bidder who set the maximum first",
+			increment: 10,
+			amount:    300,
+			proxies: []types.ProxyBid{
+				{UserID: "bidder", MaxAmount: 500},
+				{UserID: "rival", MaxAmount: 500},
+			},
+			want: []Placement{
+				{UserID: "rival", Price: 500, Auto: true},
+				{UserID: "bidder", Price: 500, Auto: true},
+			},
+		},
+		{
+			name:      "bidder maximum beats the rival by the increment",
+			increment: 10,
+			amount:    300,
+			proxies: []types.ProxyBid{
+				{UserID: "bidder", MaxAmount: 600},
+				{UserID: "rival", MaxAmount: 400},
+			},
+			want: []Placement{
+				{UserID: "rival", Price: 400, Auto: true},
+				{UserID: "bidder", Price: 410, Auto: true},
+			},
+		},
+		{
+			name:      "bid beats the rival maximum without a proxy",
+			increment: 10,
+			amount:    405,
+			proxies:   []types.ProxyBid{{UserID: "rival", MaxAmount: 400}},
+			want:      []Placement{{UserID: "bidder", Price: 405}},
+		},
+		{
+			name:      "missing increment counts as one",
+			increment: 0,
+			amount:    300,
+			proxies:   []types.ProxyBid{{UserID: "rival", MaxAmount: 500}},
+			want: []Placement{
+				{UserID: "bidder", Price: 300},
+				{UserID: "rival", Price: 301, Auto: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ResolveProxyBids(tt.increment, "bidder", tt.amount, tt.proxies)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ResolveProxyBids() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
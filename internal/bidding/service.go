@@ -0,0 +1,213 @@
+package bidding
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Martin-Hayot/auction-server/configs"
+	"github.com/Martin-Hayot/auction-server/internal/database"
+	"github.com/Martin-Hayot/auction-server/internal/lifecycle"
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+	"github.com/charmbracelet/log"
+)
+
+// Bidder is the user placing a bid.
+type Bidder struct {
+	ID   string
+	Role string
+}
+
+// Bid is a bid as submitted, whatever the transport it came through.
+// MaxAmount is optional; when set, it is kept secret and the bid is raised on the
+// bidder's behalf up to that amount whenever someone else bids.
+// IdempotencyKey is optional; a bid resubmitted with a key the bidder already
+// used is not placed again and the original bid is returned.
+type Bid struct {
+	AuctionID      string
+	Amount         int
+	MaxAmount      int
+	IdempotencyKey string
+}
+
+// Result is the outcome of a bid committed to the database.
+type Result struct {
+	Auction    types.Auctions
+	Placements []Placement
+	BidID      string // ID of the last bid recorded for the bidder
+	Outbid     string // Previous leader who lost the lead, if any
+	Extended   bool   // The bid pushed the end date back
+	Sold       bool   // The bid reached the maximum price
+	Duplicate  bool   // The idempotency key was already used, nothing was placed
+}
+
+// Leading reports whether the user holds the current bid after the bid was placed.
+func (r Result) Leading(userID string) bool {
+	return r.Auction.CurrentBidderID != nil && *r.Auction.CurrentBidderID == userID
+}
+
+// Service places bids. It holds the rules shared by every transport, leaving the
+// broadcasting of the result to the caller.
+type Service struct {
+	db        database.Service
+	softClose SoftClose
+}
+
+// NewService creates a bidding service with the soft-close rules of the configuration.
+func NewService(db database.Service, cfg *configs.Config) *Service {
+	return &Service{
+		db: db,
+		softClose: SoftClose{
+			Window:       configs.Duration(cfg.Auction.SoftCloseWindow, 2*time.Minute),
+			Extension:    configs.Duration(cfg.Auction.Extension, 2*time.Minute),
+			MaxExtension: configs.Duration(cfg.Auction.MaxExtension, 30*time.Minute),
+		},
+	}
+}
+
+// PlaceBid validates a bid and records it with the proxy bids it triggers in a single transaction,
+// retried on serialization failures. The bid is committed when it returns without error.
+func (s *Service) PlaceBid(ctx context.Context, bidder Bidder, bid Bid) (Result, *errors.AppError) {
+	if bid.MaxAmount != 0 && bid.MaxAmount < bid.Amount {
+		return Result{}, errors.New(errors.ErrBadMessageFormat, "Maximum bid must be at least the bid amount")
+	}
+
+	var result Result
+	err := s.db.RunInTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		result, err = s.placeBidTx(ctx, tx, bidder, bid)
+		return err
+	})
+	if err != nil {
+		var appErr *errors.AppError
+		if errors.As(err, &appErr) && appErr.Code != 0 && appErr.Code != errors.ErrInternalServer {
+			return Result{}, appErr
+		}
+		log.Error("Error placing bid: ", err)
+		return Result{}, errors.Internal(err)
+	}
+	return result, nil
+}
+
+// placeBidTx runs one attempt of PlaceBid within tx.
+// Rejected bids are reported as *errors.AppError.
+func (s *Service) placeBidTx(ctx context.Context, tx *sql.Tx, bidder Bidder, bid Bid) (Result, error) {
+	var result Result
+
+	auction, err := s.db.GetAuctionByIdTx(ctx, tx, bid.AuctionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Result{}, errors.New(errors.ErrAuctionNotFound, "Auction not found")
+		}
+		return Result{}, err
+	}
+
+	// Replayed submission: hand back the original bid. The auction row lock serializes
+	// submissions to this auction, a key used concurrently on another one is rejected
+	// by the unique index when the bid is created.
+	if bid.IdempotencyKey != "" {
+		original, err := s.db.GetBidByIdempotencyKeyTx(ctx, tx, bidder.ID, bid.IdempotencyKey)
+		switch {
+		case err == nil && original.AuctionID != auction.ID:
+			return Result{}, errors.New(errors.ErrIdempotencyKeyReused, "Idempotency key already used for another auction")
+		case err == nil:
+			log.Debugf("Duplicate bid %s from user %s", original.ID, bidder.ID)
+			return Result{Auction: auction, BidID: original.ID, Duplicate: true}, nil
+		case !errors.Is(err, sql.ErrNoRows):
+			return Result{}, err
+		}
+	}
+
+	now := time.Now()
+	if lifecycle.OpenIfDue(&auction, now) {
+		log.Debugf("Auction %s is live", auction.ID)
+	}
+	if appErr := lifecycle.AcceptsBids(auction, now); appErr != nil {
+		return Result{}, appErr
+	}
+
+	if appErr := CheckEligibility(auction, bidder.Role); appErr != nil {
+		return Result{}, appErr
+	}
+
+	if appErr := ValidateBid(auction, bid.Amount); appErr != nil {
+		return Result{}, appErr
+	}
+
+	// Store the secret maximum before resolving so it competes with the others
+	if bid.MaxAmount > 0 {
+		_, err = s.db.UpsertProxyBidTx(ctx, tx, types.ProxyBid{
+			AuctionID: auction.ID,
+			UserID:    bidder.ID,
+			MaxAmount: CapMaxAmount(auction, bid.MaxAmount),
+		})
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	proxies, err := s.db.GetProxyBidsTx(ctx, tx, auction.ID)
+	if err != nil {
+		return Result{}, err
+	}
+	result.Placements = ResolveProxyBids(auction.BidIncrement, bidder.ID, bid.Amount, proxies)
+
+	// Create new bids
+	for _, placement := range result.Placements {
+		record := types.Bid{
+			AuctionID: auction.ID,
+			UserID:    placement.UserID,
+			Price:     placement.Price,
+		}
+		if placement.UserID == bidder.ID && bid.IdempotencyKey != "" {
+			record.IdempotencyKey = &bid.IdempotencyKey
+		}
+		record, err = s.db.CreateBidTx(ctx, tx, record)
+		if err != nil {
+			return Result{}, err
+		}
+		if placement.UserID == bidder.ID {
+			result.BidID = record.ID
+		}
+	}
+
+	// Update auction with the leading bid
+	leading := result.Placements[len(result.Placements)-1]
+	if auction.CurrentBidderID != nil && *auction.CurrentBidderID != leading.UserID {
+		result.Outbid = *auction.CurrentBidderID
+	}
+	auction.CurrentBid = leading.Price
+	auction.CurrentBidderID = &leading.UserID
+	auction.BiddersCount++
+
+	// Buy-it-now: reaching the maximum price closes the auction immediately
+	result.Sold = auction.MaxPrice > 0 && leading.Price >= auction.MaxPrice
+	if result.Sold {
+		if appErr := lifecycle.Transition(&auction, types.StatusSold); appErr != nil {
+			return Result{}, errors.Internal(appErr)
+		}
+		auction.WinnerID = &leading.UserID
+	}
+
+	auction, err = s.db.UpdateAuctionByIdTx(ctx, tx, auction)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// Anti-sniping: late bids push the end date back
+	if !result.Sold {
+		var endDate time.Time
+		if endDate, result.Extended = s.softClose.Extend(auction, now); result.Extended {
+			updated, err := s.db.ExtendAuctionTx(ctx, tx, auction.ID, endDate)
+			if err != nil {
+				return Result{}, err
+			}
+			auction.EndDate = updated.EndDate
+			auction.OriginalEndDate = updated.OriginalEndDate
+		}
+	}
+
+	result.Auction = auction
+	return result, nil
+}
@@ -0,0 +1,45 @@
+package bidding
+
+import (
+	"time"
+
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+)
+
+// SoftClose holds the anti-sniping rules: a bid placed within Window of the
+// end date pushes the end date back by Extension, never further than
+// MaxExtension past the originally scheduled end date.
+type SoftClose struct {
+	Window       time.Duration
+	Extension    time.Duration
+	MaxExtension time.Duration
+}
+
+// Extend returns the new end date of the auction for a bid placed at now.
+// The boolean is false when the bid does not extend the auction.
+func (s SoftClose) Extend(auction types.Auctions, now time.Time) (time.Time, bool) {
+	if s.Window <= 0 || s.Extension <= 0 {
+		return time.Time{}, false
+	}
+
+	remaining := auction.EndDate.Sub(now)
+	if remaining < 0 || remaining > s.Window {
+		return time.Time{}, false
+	}
+
+	endDate := auction.EndDate.Add(s.Extension)
+	if s.MaxExtension > 0 {
+		originalEndDate := auction.EndDate
+		if auction.OriginalEndDate != nil {
+			originalEndDate = *auction.OriginalEndDate
+		}
+		if limit := originalEndDate.Add(s.MaxExtension); endDate.After(limit) {
+			endDate = limit
+		}
+	}
+
+	if !endDate.After(auction.EndDate) {
+		return time.Time{}, false
+	}
+	return endDate, true
+}
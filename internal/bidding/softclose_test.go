@@ -0,0 +1,71 @@
+package bidding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+)
+
+func TestSoftCloseExtend(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	rules := SoftClose{Window: 2 * time.Minute, Extension: 2 * time.Minute, MaxExtension: 5 * time.Minute}
+	extendedOnce := now.Add(-time.Minute)        // Scheduled end of an auction extended once
+	extendedTwice := now.Add(-3 * time.Minute)   // Scheduled end of an auction extended twice
+	extendedToLimit := now.Add(-4 * time.Minute) // Scheduled end of an auction extended to the limit
+
+	tests := []struct {
+		name    string
+		rules   SoftClose
+		auction types.Auctions
+		want    time.Time // Zero when the auction is not extended
+	}{
+		{
+			name:    "bid before the window",
+			rules:   rules,
+			auction: types.Auctions{EndDate: now.Add(3 * time.Minute)},
+		},
+		{
+			name:    "bid within the window",
+			rules:   rules,
+			auction: types.Auctions{EndDate: now.Add(time.Minute)},
+			want:    now.Add(3 * time.Minute),
+		},
+		{
+			name:    "bid after the end",
+			rules:   rules,
+			auction: types.Auctions{EndDate: now.Add(-time.Second)},
+		},
+		{
+			name:    "extension of an extended auction",
+			rules:   rules,
+			auction: types.Auctions{EndDate: now.Add(time.Minute), OriginalEndDate: &extendedOnce},
+			want:    now.Add(3 * time.Minute),
+		},
+		{
+			name:    "extension capped past the scheduled end",
+			rules:   rules,
+			auction: types.Auctions{EndDate: now.Add(time.Minute), OriginalEndDate: &extendedTwice},
+			want:    now.Add(2 * time.Minute),
+		},
+		{
+			name:    "no extension past the limit",
+			rules:   rules,
+			auction: types.Auctions{EndDate: now.Add(time.Minute), OriginalEndDate: &extendedToLimit},
+		},
+		{
+			name:    "disabled without a window",
+			rules:   SoftClose{Extension: 2 * time.Minute},
+			auction: types.Auctions{EndDate: now.Add(time.Minute)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.rules.Extend(tt.auction, now)
+			if ok != !tt.want.IsZero() || !got.Equal(tt.want) {
+				t.Errorf("Extend() = %v, %t, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
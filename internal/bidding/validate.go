@@ -0,0 +1,92 @@
+package bidding
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+)
+
+// MinimumBid returns the lowest amount the auction currently accepts.
+// The first bid may be placed at the start price, every following bid must
+// beat the current one by at least the bid increment. Near the maximum price,
+// the maximum price itself is always accepted.
+func MinimumBid(auction types.Auctions) int {
+	minimum := auction.CurrentBid + max(auction.BidIncrement, 1)
+	if auction.CurrentBidderID == nil {
+		minimum = max(auction.StartPrice, auction.CurrentBid+1)
+	}
+	if auction.MaxPrice > 0 {
+		return min(minimum, auction.MaxPrice)
+	}
+	return minimum
+}
+
+// ValidateBid checks a bid amount against the pricing rules of the auction.
+// It returns nil when the bid is acceptable.
+func ValidateBid(auction types.Auctions, amount int) *errors.AppError {
+	if amount < auction.StartPrice {
+		return errors.New(errors.ErrBidBelowStartPrice,
+			fmt.Sprintf("Bid amount must be at least the start price of %d", auction.StartPrice))
+	}
+
+	if amount <= auction.CurrentBid {
+		return errors.New(errors.ErrBidTooLow, "Bid amount must be higher than current price")
+	}
+
+	if minimum := MinimumBid(auction); amount < minimum {
+		return errors.New(errors.ErrBidIncrementTooSmall,
+			fmt.Sprintf("Bid amount must be at least %d", minimum))
+	}
+
+	if auction.MaxPrice > 0 && amount > auction.MaxPrice {
+		return errors.New(errors.ErrBidAboveMaxPrice,
+			fmt.Sprintf("Bid amount cannot exceed the maximum price of %d", auction.MaxPrice))
+	}
+
+	return nil
+}
+
+// CheckEligibility checks that a user with the given role may bid on the auction.
+// It returns nil when the user is allowed to bid.
+func CheckEligibility(auction types.Auctions, role string) *errors.AppError {
+	if auction.OnlyForMerchants && !IsMerchant(role) {
+		return errors.New(errors.ErrMerchantOnly, "Auction is reserved to merchants")
+	}
+	return nil
+}
+
+// ListedStatuses are the statuses of auctions shown to users. Drafts are not public.
+var ListedStatuses = []types.AuctionStatus{
+	types.StatusScheduled,
+	types.StatusLive,
+	types.StatusPaused,
+	types.StatusEnded,
+	types.StatusSold,
+	types.StatusReserveNotMet,
+	types.StatusCancelled,
+}
+
+// CheckVisibility checks that a user with the given role may see the auction, whatever
+// the transport. Drafts are reported as not found. It returns nil when the auction is visible.
+func CheckVisibility(auction types.Auctions, role string) *errors.AppError {
+	if !slices.Contains(ListedStatuses, auction.Status) {
+		return errors.New(errors.ErrAuctionNotFound, "Auction not found")
+	}
+	return CheckEligibility(auction, role)
+}
+
+// IsMerchant reports whether the role is the merchant role.
+func IsMerchant(role string) bool {
+	return strings.EqualFold(role, types.RoleMerchant)
+}
+
+// CapMaxAmount limits a proxy maximum to the auction's maximum price.
+func CapMaxAmount(auction types.Auctions, maxAmount int) int {
+	if auction.MaxPrice > 0 && maxAmount > auction.MaxPrice {
+		return auction.MaxPrice
+	}
+	return maxAmount
+}
@@ -0,0 +1,90 @@
+package bidding
+
+import (
+	"testing"
+
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+)
+
+func leader(userID string) *string {
+	return &userID
+}
+
+func TestMinimumBid(t *testing.T) {
+	tests := []struct {
+		name    string
+		auction types.Auctions
+		want    int
+	}{
+		{
+			name:    "first bid at the start price",
+			auction: types.Auctions{StartPrice: 1000, BidIncrement: 100},
+			want:    1000,
+		},
+		{
+			name:    "first bid above a current price past the start price",
+			auction: types.Auctions{StartPrice: 1000, CurrentBid: 1000, BidIncrement: 100},
+			want:    1001,
+		},
+		{
+			name:    "following bid beats the current one by the increment",
+			auction: types.Auctions{StartPrice: 1000, CurrentBid: 1200, BidIncrement: 100, CurrentBidderID: leader("a")},
+			want:    1300,
+		},
+		{
+			name:    "missing increment counts as one",
+			auction: types.Auctions{StartPrice: 1000, CurrentBid: 1200, CurrentBidderID: leader("a")},
+			want:    1201,
+		},
+		{
+			name: "capped at the maximum price",
+			auction: types.Auctions{
+				StartPrice: 1000, MaxPrice: 10000, CurrentBid: 9950, BidIncrement: 100, CurrentBidderID: leader("a"),
+			},
+			want: 10000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MinimumBid(tt.auction); got != tt.want {
+				t.Errorf("MinimumBid() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateBid(t *testing.T) {
+	opened := types.Auctions{StartPrice: 1000, MaxPrice: 10000, BidIncrement: 100}
+	running := types.Auctions{StartPrice: 1000, MaxPrice: 10000, CurrentBid: 2000, BidIncrement: 100, CurrentBidderID: leader("a")}
+	closeToMax := types.Auctions{StartPrice: 1000, MaxPrice: 10000, CurrentBid: 9950, BidIncrement: 100, CurrentBidderID: leader("a")}
+
+	tests := []struct {
+		name    string
+		auction types.Auctions
+		amount  int
+		want    int // Expected error code, 0 when the bid is accepted
+	}{
+		{name: "first bid at the start price", auction: opened, amount: 1000},
+		{name: "below the start price", auction: opened, amount: 999, want: errors.ErrBidBelowStartPrice},
+		{name: "equal to the current bid", auction: running, amount: 2000, want: errors.ErrBidTooLow},
+		{name: "below the increment", auction: running, amount: 2050, want: errors.ErrBidIncrementTooSmall},
+		{name: "at the increment", auction: running, amount: 2100},
+		{name: "above the maximum price", auction: running, amount: 10001, want: errors.ErrBidAboveMaxPrice},
+		{name: "maximum price within the increment", auction: closeToMax, amount: 10000},
+		{name: "past the maximum price within the increment", auction: closeToMax, amount: 10050, want: errors.ErrBidAboveMaxPrice},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appErr := ValidateBid(tt.auction, tt.amount)
+			switch {
+			case tt.want == 0 && appErr != nil:
+				t.Errorf("ValidateBid(%d) = %v, want nil", tt.amount, appErr)
+			case tt.want != 0 && (appErr == nil || appErr.Code != tt.want):
+				t.Errorf("ValidateBid(%d) = %v, want code %d", tt.amount, appErr, tt.want)
+			}
+		})
+	}
+}
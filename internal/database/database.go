@@ -4,13 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math/rand/v2"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/Martin-Hayot/auction-server/configs"
 	"github.com/Martin-Hayot/auction-server/pkg/errors"
 	"github.com/Martin-Hayot/auction-server/pkg/types"
 	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -32,20 +35,43 @@ type Service interface {
 	GetCurrentAuctions() ([]types.Auctions, error)
 	GetAuctionById(auctionID string) (types.Auctions, error)
 	UpdateAuctionById(types.Auctions) (types.Auctions, error)
-	GetAuctionsByClientId() (types.Auctions, error)
+	GetAuctionsByClientId(userID string) ([]types.ClientAuction, error)
 	CreateBid(types.Bid) (types.Bid, error)
+	GetRecentBids(auctionID string, limit int) ([]types.Bid, error)
+	ListAuctions(filter AuctionFilter) ([]types.Auctions, error)
+	GetBidsByAuctionId(auctionID string, before *BidCursor, limit int) ([]types.Bid, error)
 
 	// TRANSACTION METHODS
 	BeginTx(ctx context.Context) (*sql.Tx, error)
+	RunInTx(ctx context.Context, fn func(tx *sql.Tx) error) error
 	GetAuctionByIdTx(ctx context.Context, tx *sql.Tx, auctionID string) (types.Auctions, error)
 	UpdateAuctionByIdTx(ctx context.Context, tx *sql.Tx, auction types.Auctions) (types.Auctions, error)
 	CreateBidTx(ctx context.Context, tx *sql.Tx, bid types.Bid) (types.Bid, error)
+	GetBidByIdempotencyKeyTx(ctx context.Context, tx *sql.Tx, userID string, key string) (types.Bid, error)
+	UpdateAuctionStatusTx(ctx context.Context, tx *sql.Tx, auction types.Auctions, from types.AuctionStatus) (types.Auctions, error)
+	ExtendAuctionTx(ctx context.Context, tx *sql.Tx, auctionID string, endDate time.Time) (types.Auctions, error)
+	UpsertProxyBidTx(ctx context.Context, tx *sql.Tx, proxy types.ProxyBid) (types.ProxyBid, error)
+	GetProxyBidsTx(ctx context.Context, tx *sql.Tx, auctionID string) ([]types.ProxyBid, error)
 }
 
 type service struct {
-	db *sql.DB
+	db               *sql.DB
+	txRetries        int64 // Transactions retried after a serialization failure or deadlock
+	txRetryExhausted int64 // Transactions that still failed after the last attempt
 }
 
+const (
+	maxTxAttempts  = 5                      // Attempts of a transaction before giving up
+	txBackoffBase  = 10 * time.Millisecond  // Backoff before the first retry
+	txBackoffLimit = 200 * time.Millisecond // Upper bound of the backoff between retries
+
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+	sqlStateUniqueViolation      = "23505"
+
+	bidIdempotencyKeyIndex = "Bid_userId_idempotencyKey_key" // Unique index of the keys of a bidder
+)
+
 var dbInstance *service
 
 func Get() service {
@@ -73,7 +99,8 @@ func New(cfg *configs.Config) Service {
 		log.Fatal("Error connecting with gorm: ", err)
 	}
 
-	if err != nil {
+	// Apply the server's own schema changes, the rest of the schema is managed by Prisma
+	if err = migrate(context.Background(), db); err != nil {
 		log.Fatal("Error with migration: ", err)
 	}
 
@@ -96,7 +123,7 @@ func (s *service) Health() map[string]string {
 	if err != nil {
 		stats["status"] = "down"
 		stats["error"] = fmt.Sprintf("db down: %v", err)
-		log.Fatalf("db down: %v", err) // Log the error and terminate the program
+		log.Errorf("db down: %v", err) // Served over HTTP, the server keeps running
 		return stats
 	}
 
@@ -114,6 +141,9 @@ func (s *service) Health() map[string]string {
 	stats["max_idle_closed"] = strconv.FormatInt(dbStats.MaxIdleClosed, 10)
 	stats["max_lifetime_closed"] = strconv.FormatInt(dbStats.MaxLifetimeClosed, 10)
 
+	stats["tx_retries"] = strconv.FormatInt(atomic.LoadInt64(&s.txRetries), 10)
+	stats["tx_retry_exhausted"] = strconv.FormatInt(atomic.LoadInt64(&s.txRetryExhausted), 10)
+
 	// Evaluate stats to provide a health message
 	if dbStats.OpenConnections > 40 { // Assuming 50 is the max for this example
 		stats["message"] = "The database is experiencing heavy load."
@@ -170,6 +200,7 @@ func (s *service) GetAuctionById(auctionID string) (types.Auctions, error) {
             "seats", 
             "startDate", 
             "endDate", 
+            "originalEndDate", 
             "startPrice", 
             "maxPrice", 
             "reservePrice", 
@@ -201,6 +232,7 @@ func (s *service) GetAuctionById(auctionID string) (types.Auctions, error) {
 		&auction.Seats,
 		&auction.StartDate,
 		&auction.EndDate,
+		&auction.OriginalEndDate,
 		&auction.StartPrice,
 		&auction.MaxPrice,
 		&auction.ReservePrice,
@@ -263,9 +295,76 @@ func (s *service) UpdateAuctionById(auction types.Auctions) (types.Auctions, err
 	return auction, nil
 }
 
-func (s *service) GetAuctionsByClientId() (types.Auctions, error) {
-	var auction types.Auctions
-	return auction, nil
+// GetAuctionsByClientId retrieves every auction a user has bid on with the user's highest bid,
+// most recently ending first. Winning and Outcome are left to the bidding service.
+func (s *service) GetAuctionsByClientId(userID string) ([]types.ClientAuction, error) {
+	var clientAuctions []types.ClientAuction
+	query := `
+        SELECT a."id", a."mileage", a."state", a."circulationDate", a."fuelType", a."power", a."transmission",
+            a."carBody", a."gearBox", a."color", a."doors", a."seats", a."startDate", a."endDate",
+            a."originalEndDate", a."startPrice", a."maxPrice", a."reservePrice", a."currentBid",
+            a."bidIncrement", a."currentBidderId", a."biddersCount", a."winnerId", a."onlyForMerchants",
+            a."status", a."carId", a."createdAt", a."updatedAt", b."highestBid"
+        FROM (
+            SELECT "auctionId", MAX("price") AS "highestBid"
+            FROM public."Bid"
+            WHERE "userId" = $1
+            GROUP BY "auctionId"
+        ) b
+        JOIN public."Auctions" a ON a."id" = b."auctionId"
+        ORDER BY a."endDate" DESC, a."id" ASC
+    `
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting auctions by client id: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var clientAuction types.ClientAuction
+		auction := &clientAuction.Auction
+		err := rows.Scan(
+			&auction.ID,
+			&auction.Mileage,
+			&auction.State,
+			&auction.CirculationDate,
+			&auction.FuelType,
+			&auction.Power,
+			&auction.Transmission,
+			&auction.CarBody,
+			&auction.GearBox,
+			&auction.Color,
+			&auction.Doors,
+			&auction.Seats,
+			&auction.StartDate,
+			&auction.EndDate,
+			&auction.OriginalEndDate,
+			&auction.StartPrice,
+			&auction.MaxPrice,
+			&auction.ReservePrice,
+			&auction.CurrentBid,
+			&auction.BidIncrement,
+			&auction.CurrentBidderID,
+			&auction.BiddersCount,
+			&auction.WinnerID,
+			&auction.OnlyForMerchants,
+			&auction.Status,
+			&auction.CarID,
+			&auction.CreatedAt,
+			&auction.UpdatedAt,
+			&clientAuction.HighestBid,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning client auction: %w", err)
+		}
+		clientAuctions = append(clientAuctions, clientAuction)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over client auctions: %w", err)
+	}
+
+	return clientAuctions, nil
 }
 
 func (s *service) CreateBid(types.Bid) (types.Bid, error) {
@@ -288,10 +387,43 @@ func (s *service) CreateBid(types.Bid) (types.Bid, error) {
 	return bid, nil
 }
 
+// GetRecentBids retrieves the latest bids of an auction, newest first.
+func (s *service) GetRecentBids(auctionID string, limit int) ([]types.Bid, error) {
+	var bids []types.Bid
+	query := `SELECT "id", "auctionId", "userId", "price", "createdAt", "updatedAt" FROM public."Bid" WHERE "auctionId" = $1 ORDER BY "createdAt" DESC, "price" DESC LIMIT $2`
+	rows, err := s.db.Query(query, auctionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting recent bids: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bid types.Bid
+		err := rows.Scan(
+			&bid.ID,
+			&bid.AuctionID,
+			&bid.UserID,
+			&bid.Price,
+			&bid.CreatedAt,
+			&bid.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning bid: %w", err)
+		}
+		bids = append(bids, bid)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over bids: %w", err)
+	}
+
+	return bids, nil
+}
+
 func (s *service) GetCurrentAuctions() ([]types.Auctions, error) {
 	var auctions []types.Auctions
-	query := `SELECT "id", "mileage", "state", "circulationDate", "fuelType", "power", "transmission", "carBody", "gearBox", "color", "doors", "seats", "startDate", "endDate", "startPrice", "maxPrice", "reservePrice", "currentBid", "bidIncrement", "currentBidderId", "biddersCount", "winnerId", "onlyForMerchants", "status", "carId", "createdAt", "updatedAt" FROM public."Auctions" ORDER BY "startDate" ASC LIMIT 1`
-	rows, err := s.db.Query(query)
+	query := `SELECT "id", "mileage", "state", "circulationDate", "fuelType", "power", "transmission", "carBody", "gearBox", "color", "doors", "seats", "startDate", "endDate", "startPrice", "maxPrice", "reservePrice", "currentBid", "bidIncrement", "currentBidderId", "biddersCount", "winnerId", "onlyForMerchants", "status", "carId", "createdAt", "updatedAt" FROM public."Auctions" WHERE "status" IN ($1, $2, $3) ORDER BY "startDate" ASC`
+	rows, err := s.db.Query(query, types.StatusScheduled, types.StatusLive, types.StatusPaused)
 	if err != nil {
 		return nil, fmt.Errorf("error getting current auctions: %w", err)
 	}
@@ -350,6 +482,78 @@ func (s *service) BeginTx(ctx context.Context) (*sql.Tx, error) {
 	return tx, nil
 }
 
+// RunInTx runs fn in a serializable transaction, committing when fn returns nil
+// and rolling back otherwise. Transactions aborted by a serialization failure or
+// a deadlock are retried with jittered exponential backoff, so fn must not have
+// side effects outside of tx.
+func (s *service) RunInTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = s.runTxOnce(ctx, fn)
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+		if attempt == maxTxAttempts {
+			atomic.AddInt64(&s.txRetryExhausted, 1)
+			log.Warnf("Transaction failed after %d attempts: %v", attempt, err)
+			return err
+		}
+
+		atomic.AddInt64(&s.txRetries, 1)
+		backoff := min(txBackoffBase<<(attempt-1), txBackoffLimit)
+		backoff = backoff/2 + rand.N(backoff/2+1)
+		log.Debugf("Retrying transaction in %v (attempt %d): %v", backoff, attempt, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// runTxOnce runs fn in a single transaction.
+func (s *service) runTxOnce(ctx context.Context, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// isRetryable reports whether the transaction failed because of a serialization failure or a deadlock.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == sqlStateSerializationFailure || pgErr.Code == sqlStateDeadlockDetected
+}
+
+// isUniqueViolation reports whether the statement failed because it broke the unique constraint or index.
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == sqlStateUniqueViolation && pgErr.ConstraintName == constraint
+}
+
 // GetAuctionByIdTx retrieves an auction by its ID within a transaction.
 func (s *service) GetAuctionByIdTx(ctx context.Context, tx *sql.Tx, auctionID string) (types.Auctions, error) {
 	var auction types.Auctions
@@ -360,7 +564,15 @@ func (s *service) GetAuctionByIdTx(ctx context.Context, tx *sql.Tx, auctionID st
             "currentBidderId", 
             "biddersCount", 
             "status", 
-            "reservePrice" 
+            "reservePrice", 
+            "startPrice", 
+            "maxPrice", 
+            "bidIncrement", 
+            "winnerId", 
+            "startDate", 
+            "endDate", 
+            "originalEndDate", 
+            "onlyForMerchants" 
         FROM public."Auctions" 
         WHERE "id" = $1 FOR UPDATE
     `
@@ -371,6 +583,14 @@ func (s *service) GetAuctionByIdTx(ctx context.Context, tx *sql.Tx, auctionID st
 		&auction.BiddersCount,
 		&auction.Status,
 		&auction.ReservePrice,
+		&auction.StartPrice,
+		&auction.MaxPrice,
+		&auction.BidIncrement,
+		&auction.WinnerID,
+		&auction.StartDate,
+		&auction.EndDate,
+		&auction.OriginalEndDate,
+		&auction.OnlyForMerchants,
 	)
 	if err != nil {
 		return types.Auctions{}, fmt.Errorf("error getting auction by id in tx: %w", err)
@@ -382,17 +602,18 @@ func (s *service) GetAuctionByIdTx(ctx context.Context, tx *sql.Tx, auctionID st
 func (s *service) UpdateAuctionByIdTx(ctx context.Context, tx *sql.Tx, auction types.Auctions) (types.Auctions, error) {
 	query := `
         UPDATE public."Auctions" 
-        SET "currentBid" = $1, "currentBidderId" = $2, "biddersCount" = $3 
-        WHERE "id" = $4 
-        RETURNING "id", "currentBid", "currentBidderId", "biddersCount", "status", "reservePrice"
+        SET "currentBid" = $1, "currentBidderId" = $2, "biddersCount" = $3, "status" = $4, "winnerId" = $5 
+        WHERE "id" = $6 
+        RETURNING "id", "currentBid", "currentBidderId", "biddersCount", "status", "reservePrice", "winnerId"
     `
-	err := tx.QueryRowContext(ctx, query, auction.CurrentBid, auction.CurrentBidderID, auction.BiddersCount, auction.ID).Scan(
+	err := tx.QueryRowContext(ctx, query, auction.CurrentBid, auction.CurrentBidderID, auction.BiddersCount, auction.Status, auction.WinnerID, auction.ID).Scan(
 		&auction.ID,
 		&auction.CurrentBid,
 		&auction.CurrentBidderID,
 		&auction.BiddersCount,
 		&auction.Status,
 		&auction.ReservePrice,
+		&auction.WinnerID,
 	)
 	if err != nil {
 		return types.Auctions{}, fmt.Errorf("error updating auction by id in tx: %w", err)
@@ -404,20 +625,158 @@ func (s *service) UpdateAuctionByIdTx(ctx context.Context, tx *sql.Tx, auction t
 func (s *service) CreateBidTx(ctx context.Context, tx *sql.Tx, bid types.Bid) (types.Bid, error) {
 	var returnedBid types.Bid
 	query := `
-        INSERT INTO public."Bid" ("id", "auctionId", "userId", "price", "updatedAt") 
-        VALUES (gen_random_uuid(), $1, $2, $3, now()) 
-        RETURNING "id", "auctionId", "userId", "price", "createdAt", "updatedAt"
+        INSERT INTO public."Bid" ("id", "auctionId", "userId", "price", "idempotencyKey", "updatedAt") 
+        VALUES (gen_random_uuid(), $1, $2, $3, $4, now()) 
+        RETURNING "id", "auctionId", "userId", "price", "idempotencyKey", "createdAt", "updatedAt"
     `
-	err := tx.QueryRowContext(ctx, query, bid.AuctionID, bid.UserID, bid.Price).Scan(
+	err := tx.QueryRowContext(ctx, query, bid.AuctionID, bid.UserID, bid.Price, bid.IdempotencyKey).Scan(
 		&returnedBid.ID,
 		&returnedBid.AuctionID,
 		&returnedBid.UserID,
 		&returnedBid.Price,
+		&returnedBid.IdempotencyKey,
 		&returnedBid.CreatedAt,
 		&returnedBid.UpdatedAt,
 	)
 	if err != nil {
+		// Another auction took the key concurrently, its row lock does not serialize with ours
+		if isUniqueViolation(err, bidIdempotencyKeyIndex) {
+			return types.Bid{}, errors.New(errors.ErrIdempotencyKeyReused, "Idempotency key already used for another auction")
+		}
 		return types.Bid{}, fmt.Errorf("error creating bid in tx: %w", err)
 	}
 	return returnedBid, nil
 }
+
+// GetBidByIdempotencyKeyTx retrieves the bid a user submitted with an idempotency key within a transaction.
+// It returns sql.ErrNoRows when the key was never used.
+func (s *service) GetBidByIdempotencyKeyTx(ctx context.Context, tx *sql.Tx, userID string, key string) (types.Bid, error) {
+	var bid types.Bid
+	query := `
+        SELECT "id", "auctionId", "userId", "price", "idempotencyKey", "createdAt", "updatedAt" 
+        FROM public."Bid" 
+        WHERE "userId" = $1 AND "idempotencyKey" = $2
+    `
+	err := tx.QueryRowContext(ctx, query, userID, key).Scan(
+		&bid.ID,
+		&bid.AuctionID,
+		&bid.UserID,
+		&bid.Price,
+		&bid.IdempotencyKey,
+		&bid.CreatedAt,
+		&bid.UpdatedAt,
+	)
+	if err != nil {
+		return types.Bid{}, fmt.Errorf("error getting bid by idempotency key in tx: %w", err)
+	}
+	return bid, nil
+}
+
+// UpdateAuctionStatusTx persists a status change and the winner of an auction within a transaction.
+// The update only applies if the auction is still in the from status, otherwise sql.ErrNoRows is returned.
+func (s *service) UpdateAuctionStatusTx(ctx context.Context, tx *sql.Tx, auction types.Auctions, from types.AuctionStatus) (types.Auctions, error) {
+	query := `
+        UPDATE public."Auctions" 
+        SET "status" = $1, "winnerId" = $2, "updatedAt" = now() 
+        WHERE "id" = $3 AND "status" = $4 
+        RETURNING "id", "currentBid", "currentBidderId", "biddersCount", "status", "reservePrice", "winnerId"
+    `
+	err := tx.QueryRowContext(ctx, query, auction.Status, auction.WinnerID, auction.ID, from).Scan(
+		&auction.ID,
+		&auction.CurrentBid,
+		&auction.CurrentBidderID,
+		&auction.BiddersCount,
+		&auction.Status,
+		&auction.ReservePrice,
+		&auction.WinnerID,
+	)
+	if err != nil {
+		return types.Auctions{}, fmt.Errorf("error updating auction status in tx: %w", err)
+	}
+	return auction, nil
+}
+
+// ExtendAuctionTx moves the end date of an auction within a transaction.
+// The scheduled end date is kept in "originalEndDate" on the first extension.
+func (s *service) ExtendAuctionTx(ctx context.Context, tx *sql.Tx, auctionID string, endDate time.Time) (types.Auctions, error) {
+	var auction types.Auctions
+	query := `
+        UPDATE public."Auctions" 
+        SET "originalEndDate" = COALESCE("originalEndDate", "endDate"), "endDate" = $1 
+        WHERE "id" = $2 
+        RETURNING "id", "endDate", "originalEndDate"
+    `
+	err := tx.QueryRowContext(ctx, query, endDate, auctionID).Scan(
+		&auction.ID,
+		&auction.EndDate,
+		&auction.OriginalEndDate,
+	)
+	if err != nil {
+		return types.Auctions{}, fmt.Errorf("error extending auction in tx: %w", err)
+	}
+	return auction, nil
+}
+
+// UpsertProxyBidTx stores a bidder's maximum bid for an auction within a transaction.
+// A bidder has a single maximum per auction; submitting a new one replaces it.
+func (s *service) UpsertProxyBidTx(ctx context.Context, tx *sql.Tx, proxy types.ProxyBid) (types.ProxyBid, error) {
+	var returnedProxy types.ProxyBid
+	query := `
+        INSERT INTO public."ProxyBid" ("id", "auctionId", "userId", "maxAmount", "updatedAt") 
+        VALUES (gen_random_uuid(), $1, $2, $3, now()) 
+        ON CONFLICT ("auctionId", "userId") 
+        DO UPDATE SET "maxAmount" = EXCLUDED."maxAmount", "updatedAt" = now() 
+        RETURNING "id", "auctionId", "userId", "maxAmount", "createdAt", "updatedAt"
+    `
+	err := tx.QueryRowContext(ctx, query, proxy.AuctionID, proxy.UserID, proxy.MaxAmount).Scan(
+		&returnedProxy.ID,
+		&returnedProxy.AuctionID,
+		&returnedProxy.UserID,
+		&returnedProxy.MaxAmount,
+		&returnedProxy.CreatedAt,
+		&returnedProxy.UpdatedAt,
+	)
+	if err != nil {
+		return types.ProxyBid{}, fmt.Errorf("error upserting proxy bid in tx: %w", err)
+	}
+	return returnedProxy, nil
+}
+
+// GetProxyBidsTx retrieves the maximum bids of an auction within a transaction,
+// highest first and, for equal amounts, in the order they were set.
+func (s *service) GetProxyBidsTx(ctx context.Context, tx *sql.Tx, auctionID string) ([]types.ProxyBid, error) {
+	var proxies []types.ProxyBid
+	query := `
+        SELECT "id", "auctionId", "userId", "maxAmount", "createdAt", "updatedAt" 
+        FROM public."ProxyBid" 
+        WHERE "auctionId" = $1 
+        ORDER BY "maxAmount" DESC, "updatedAt" ASC
+    `
+	rows, err := tx.QueryContext(ctx, query, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting proxy bids in tx: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var proxy types.ProxyBid
+		err := rows.Scan(
+			&proxy.ID,
+			&proxy.AuctionID,
+			&proxy.UserID,
+			&proxy.MaxAmount,
+			&proxy.CreatedAt,
+			&proxy.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning proxy bid: %w", err)
+		}
+		proxies = append(proxies, proxy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over proxy bids: %w", err)
+	}
+
+	return proxies, nil
+}
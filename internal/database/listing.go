@@ -0,0 +1,186 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+)
+
+// AuctionFilter selects the auctions returned by ListAuctions. Zero fields do not filter.
+// Auctions are listed by end date, soonest first, and paginated with After.
+type AuctionFilter struct {
+	Statuses         []types.AuctionStatus
+	FuelType         string
+	CarBody          string
+	OnlyForMerchants *bool
+	EndsAfter        *time.Time
+	EndsBefore       *time.Time
+	After            *AuctionCursor // Only auctions listed after this one
+	Limit            int
+}
+
+// AuctionCursor is the position of an auction in a listing.
+type AuctionCursor struct {
+	EndDate time.Time
+	ID      string
+}
+
+// BidCursor is the position of a bid in a listing.
+type BidCursor struct {
+	CreatedAt time.Time
+	Price     int
+	ID        string
+}
+
+// ListAuctions retrieves the auctions matching a filter.
+func (s *service) ListAuctions(filter AuctionFilter) ([]types.Auctions, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if len(filter.Statuses) > 0 {
+		placeholders := make([]string, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			placeholders = append(placeholders, arg(status))
+		}
+		conditions = append(conditions, `"status" IN (`+strings.Join(placeholders, ", ")+`)`)
+	}
+	if filter.FuelType != "" {
+		conditions = append(conditions, `"fuelType" = `+arg(filter.FuelType))
+	}
+	if filter.CarBody != "" {
+		conditions = append(conditions, `"carBody" = `+arg(filter.CarBody))
+	}
+	if filter.OnlyForMerchants != nil {
+		conditions = append(conditions, `"onlyForMerchants" = `+arg(*filter.OnlyForMerchants))
+	}
+	if filter.EndsAfter != nil {
+		conditions = append(conditions, `"endDate" >= `+arg(*filter.EndsAfter))
+	}
+	if filter.EndsBefore != nil {
+		conditions = append(conditions, `"endDate" < `+arg(*filter.EndsBefore))
+	}
+	if filter.After != nil {
+		conditions = append(conditions, `("endDate", "id") > (`+arg(filter.After.EndDate)+`, `+arg(filter.After.ID)+`)`)
+	}
+
+	query := `
+        SELECT "id", "mileage", "state", "circulationDate", "fuelType", "power", "transmission", "carBody",
+            "gearBox", "color", "doors", "seats", "startDate", "endDate", "originalEndDate", "startPrice",
+            "maxPrice", "reservePrice", "currentBid", "bidIncrement", "currentBidderId", "biddersCount",
+            "winnerId", "onlyForMerchants", "status", "carId", "createdAt", "updatedAt"
+        FROM public."Auctions"`
+	if len(conditions) > 0 {
+		query += `
+        WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += `
+        ORDER BY "endDate" ASC, "id" ASC
+        LIMIT ` + arg(filter.Limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing auctions: %w", err)
+	}
+	defer rows.Close()
+
+	var auctions []types.Auctions
+	for rows.Next() {
+		var auction types.Auctions
+		err := rows.Scan(
+			&auction.ID,
+			&auction.Mileage,
+			&auction.State,
+			&auction.CirculationDate,
+			&auction.FuelType,
+			&auction.Power,
+			&auction.Transmission,
+			&auction.CarBody,
+			&auction.GearBox,
+			&auction.Color,
+			&auction.Doors,
+			&auction.Seats,
+			&auction.StartDate,
+			&auction.EndDate,
+			&auction.OriginalEndDate,
+			&auction.StartPrice,
+			&auction.MaxPrice,
+			&auction.ReservePrice,
+			&auction.CurrentBid,
+			&auction.BidIncrement,
+			&auction.CurrentBidderID,
+			&auction.BiddersCount,
+			&auction.WinnerID,
+			&auction.OnlyForMerchants,
+			&auction.Status,
+			&auction.CarID,
+			&auction.CreatedAt,
+			&auction.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning auction: %w", err)
+		}
+		auctions = append(auctions, auction)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over auctions: %w", err)
+	}
+
+	return auctions, nil
+}
+
+// GetBidsByAuctionId retrieves the bids of an auction, newest first, starting after
+// the given bid when before is set. Bids placed together by proxy bidding share their
+// creation date, the highest of them comes first.
+func (s *service) GetBidsByAuctionId(auctionID string, before *BidCursor, limit int) ([]types.Bid, error) {
+	query := `
+        SELECT "id", "auctionId", "userId", "price", "createdAt", "updatedAt"
+        FROM public."Bid"
+        WHERE "auctionId" = $1`
+	args := []any{auctionID, limit}
+	if before != nil {
+		query += ` AND ("createdAt", "price", "id") < ($3, $4, $5)`
+		args = append(args, before.CreatedAt, before.Price, before.ID)
+	}
+	query += `
+        ORDER BY "createdAt" DESC, "price" DESC, "id" DESC
+        LIMIT $2`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting bids by auction id: %w", err)
+	}
+	defer rows.Close()
+
+	var bids []types.Bid
+	for rows.Next() {
+		var bid types.Bid
+		err := rows.Scan(
+			&bid.ID,
+			&bid.AuctionID,
+			&bid.UserID,
+			&bid.Price,
+			&bid.CreatedAt,
+			&bid.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning bid: %w", err)
+		}
+		bids = append(bids, bid)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over bids: %w", err)
+	}
+
+	return bids, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"slices"
+
+	"github.com/charmbracelet/log"
+)
+
+// migrations holds the changes made by the server on top of the Prisma schema.
+// Files are applied once, in name order, and must not be edited once released.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLockID is the advisory lock serializing migrations across server instances.
+const migrationLockID = 7254301
+
+// migrate applies the migrations not yet recorded in "_ServerMigrations", in a single transaction.
+func migrate(ctx context.Context, db *sql.DB) error {
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return fmt.Errorf("error listing migrations: %w", err)
+	}
+	slices.Sort(names)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting migration transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("error locking migrations: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS public."_ServerMigrations" (
+            "name"      TEXT PRIMARY KEY,
+            "appliedAt" TIMESTAMP(3) NOT NULL DEFAULT now()
+        )`)
+	if err != nil {
+		return fmt.Errorf("error creating migrations table: %w", err)
+	}
+
+	for _, name := range names {
+		var applied bool
+		err = tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM public."_ServerMigrations" WHERE "name" = $1)`, name).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("error checking migration %s: %w", name, err)
+		}
+		if applied {
+			continue
+		}
+
+		script, err := migrations.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("error reading migration %s: %w", name, err)
+		}
+		if _, err = tx.ExecContext(ctx, string(script)); err != nil {
+			return fmt.Errorf("error applying migration %s: %w", name, err)
+		}
+		if _, err = tx.ExecContext(ctx, `INSERT INTO public."_ServerMigrations" ("name") VALUES ($1)`, name); err != nil {
+			return fmt.Errorf("error recording migration %s: %w", name, err)
+		}
+		log.Infof("Applied migration %s", name)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,12 @@
+-- Secret maximum bids used by the proxy bidding engine.
+-- One row per bidder and auction, raised in place when the bidder
+-- submits a higher maximum.
+CREATE TABLE IF NOT EXISTS public."ProxyBid" (
+    "id"        TEXT PRIMARY KEY DEFAULT gen_random_uuid(),
+    "auctionId" TEXT NOT NULL REFERENCES public."Auctions" ("id") ON DELETE CASCADE,
+    "userId"    TEXT NOT NULL REFERENCES public."User" ("id") ON DELETE CASCADE,
+    "maxAmount" INTEGER NOT NULL,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT now(),
+    "updatedAt" TIMESTAMP(3) NOT NULL DEFAULT now(),
+    UNIQUE ("auctionId", "userId")
+);
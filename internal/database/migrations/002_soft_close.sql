@@ -0,0 +1,3 @@
+-- End date as scheduled before any anti-sniping extension.
+-- Set on the first extension and used to cap the total extension.
+ALTER TABLE public."Auctions" ADD COLUMN IF NOT EXISTS "originalEndDate" TIMESTAMP(3);
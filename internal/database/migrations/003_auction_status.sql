@@ -0,0 +1,24 @@
+-- Map the statuses written before the lifecycle states were introduced.
+-- Known states written with another case are normalized, the others are
+-- derived from the auction itself.
+UPDATE public."Auctions"
+SET "status" = lower("status")
+WHERE lower("status") IN ('draft', 'scheduled', 'live', 'paused', 'ended', 'sold', 'reserve_not_met', 'cancelled')
+    AND "status" <> lower("status");
+
+UPDATE public."Auctions"
+SET "status" = CASE
+        WHEN "winnerId" IS NOT NULL THEN 'sold'
+        WHEN "endDate" > now() AND "startDate" > now() THEN 'scheduled'
+        WHEN "endDate" > now() THEN 'live'
+        WHEN "currentBidderId" IS NULL THEN 'ended'
+        WHEN "currentBid" < "reservePrice" THEN 'reserve_not_met'
+        ELSE 'sold'
+    END,
+    "winnerId" = CASE
+        WHEN "winnerId" IS NULL AND "endDate" <= now() AND "currentBidderId" IS NOT NULL
+            AND "currentBid" >= "reservePrice" THEN "currentBidderId"
+        ELSE "winnerId"
+    END
+WHERE "status" IS NULL
+    OR "status" NOT IN ('draft', 'scheduled', 'live', 'paused', 'ended', 'sold', 'reserve_not_met', 'cancelled');
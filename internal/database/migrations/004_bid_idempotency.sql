@@ -0,0 +1,6 @@
+-- Client-chosen key making bid submissions idempotent.
+-- A key can only be used once per bidder.
+ALTER TABLE public."Bid" ADD COLUMN IF NOT EXISTS "idempotencyKey" TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS "Bid_userId_idempotencyKey_key"
+    ON public."Bid" ("userId", "idempotencyKey")
+    WHERE "idempotencyKey" IS NOT NULL;
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/Martin-Hayot/auction-server/internal/bidding"
+	"github.com/Martin-Hayot/auction-server/internal/database"
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/charmbracelet/log"
+)
+
+// maxBodySize is the largest request body accepted, in bytes.
+const maxBodySize = 4096
+
+// Publisher broadcasts the consequences of a bid to live clients.
+type Publisher interface {
+	PublishBid(result bidding.Result)
+}
+
+// Handler serves the REST API, for clients that cannot hold a WebSocket.
+type Handler struct {
+	db        database.Service
+	bids      *bidding.Service
+	publisher Publisher
+}
+
+// NewHandler creates a REST API handler. Bids placed through it are broadcast by publisher.
+func NewHandler(db database.Service, bids *bidding.Service, publisher Publisher) *Handler {
+	return &Handler{
+		db:        db,
+		bids:      bids,
+		publisher: publisher,
+	}
+}
+
+// Register adds the API routes to mux.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/auctions", h.HandleListAuctions)
+	mux.HandleFunc("GET /api/auctions/{id}", h.HandleGetAuction)
+	mux.HandleFunc("GET /api/auctions/{id}/bids", h.HandleListBids)
+	mux.HandleFunc("POST /api/auctions/{id}/bids", h.HandlePlaceBid)
+	mux.HandleFunc("GET /api/me/auctions", h.HandleMyAuctions)
+}
+
+// writeJSON writes v as the JSON body of the response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug("Error writing response: ", err)
+	}
+}
+
+// writeError writes an application error with the HTTP status matching its code.
+func writeError(w http.ResponseWriter, appErr *errors.AppError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appErr.HTTPStatus())
+	w.Write([]byte(appErr.ToJSON()))
+}
+
+// decodeJSON reads a JSON request body into v. Requiring the JSON content type also keeps
+// cross-site forms from submitting requests with the user's cookie.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) *errors.AppError {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return errors.New(errors.ErrBadRequest, "Content-Type must be application/json")
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return errors.New(errors.ErrBadRequest, "Invalid request body")
+	}
+	return nil
+}
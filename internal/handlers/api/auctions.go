@@ -0,0 +1,303 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Martin-Hayot/auction-server/internal/auth"
+	"github.com/Martin-Hayot/auction-server/internal/bidding"
+	"github.com/Martin-Hayot/auction-server/internal/database"
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+	"github.com/charmbracelet/log"
+)
+
+// Page sizes of the listings.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// auctionsResponse is the body of an auction listing.
+type auctionsResponse struct {
+	Auctions   []types.Auctions `json:"auctions"`
+	NextCursor string           `json:"next_cursor,omitempty"` // Set when more auctions follow
+}
+
+// bidsResponse is the body of a bid listing.
+type bidsResponse struct {
+	Bids       []types.Bid `json:"bids"`
+	NextCursor string      `json:"next_cursor,omitempty"` // Set when more bids follow
+}
+
+// HandleListAuctions lists auctions by end date, soonest first. It filters on the "status"
+// (comma-separated), "fuel_type", "car_body", "only_for_merchants", "ends_after" and
+// "ends_before" (RFC 3339) query parameters, and pages with "limit" and "cursor".
+func (h *Handler) HandleListAuctions(w http.ResponseWriter, r *http.Request) {
+	viewer, appErr := h.viewer(r)
+	if appErr != nil {
+		writeError(w, appErr)
+		return
+	}
+
+	filter, appErr := parseAuctionFilter(r)
+	if appErr != nil {
+		writeError(w, appErr)
+		return
+	}
+
+	// Merchant-only auctions are hidden from everyone else
+	if !bidding.IsMerchant(viewer.Role) {
+		if filter.OnlyForMerchants != nil && *filter.OnlyForMerchants {
+			writeError(w, errors.New(errors.ErrMerchantOnly, "Auction is reserved to merchants"))
+			return
+		}
+		onlyForMerchants := false
+		filter.OnlyForMerchants = &onlyForMerchants
+	}
+
+	pageSize := filter.Limit
+	filter.Limit++ // One more tells whether a next page exists
+	auctions, err := h.db.ListAuctions(filter)
+	if err != nil {
+		log.Error("Error listing auctions: ", err)
+		writeError(w, errors.Internal(err))
+		return
+	}
+
+	response := auctionsResponse{Auctions: make([]types.Auctions, 0, len(auctions))}
+	if len(auctions) > pageSize {
+		auctions = auctions[:pageSize]
+		last := auctions[len(auctions)-1]
+		response.NextCursor = encodeCursor(last.EndDate, last.ID)
+	}
+	for _, auction := range auctions {
+		response.Auctions = append(response.Auctions, redactAuction(auction, viewer.ID))
+	}
+	writeJSON(w, http.StatusOK, &response)
+}
+
+// HandleGetAuction returns a single auction.
+func (h *Handler) HandleGetAuction(w http.ResponseWriter, r *http.Request) {
+	viewer, appErr := h.viewer(r)
+	if appErr != nil {
+		writeError(w, appErr)
+		return
+	}
+
+	auction, appErr := h.visibleAuction(viewer, r.PathValue("id"))
+	if appErr != nil {
+		writeError(w, appErr)
+		return
+	}
+	writeJSON(w, http.StatusOK, redactAuction(auction, viewer.ID))
+}
+
+// HandleListBids lists the bids of an auction, newest first, paged with "limit" and "cursor".
+// Other bidders' identities are not disclosed.
+func (h *Handler) HandleListBids(w http.ResponseWriter, r *http.Request) {
+	viewer, appErr := h.viewer(r)
+	if appErr != nil {
+		writeError(w, appErr)
+		return
+	}
+
+	auction, appErr := h.visibleAuction(viewer, r.PathValue("id"))
+	if appErr != nil {
+		writeError(w, appErr)
+		return
+	}
+
+	pageSize, appErr := parseLimit(r)
+	if appErr != nil {
+		writeError(w, appErr)
+		return
+	}
+	var before *database.BidCursor
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		bidCursor, ok := decodeBidCursor(cursor)
+		if !ok {
+			writeError(w, errors.New(errors.ErrBadRequest, "Invalid cursor"))
+			return
+		}
+		before = &bidCursor
+	}
+
+	bids, err := h.db.GetBidsByAuctionId(auction.ID, before, pageSize+1)
+	if err != nil {
+		log.Error("Error listing bids: ", err)
+		writeError(w, errors.Internal(err))
+		return
+	}
+
+	response := bidsResponse{Bids: make([]types.Bid, 0, len(bids))}
+	if len(bids) > pageSize {
+		bids = bids[:pageSize]
+		last := bids[len(bids)-1]
+		response.NextCursor = encodeBidCursor(database.BidCursor{CreatedAt: last.CreatedAt, Price: last.Price, ID: last.ID})
+	}
+	for _, bid := range bids {
+		if bid.UserID != viewer.ID {
+			bid.UserID = ""
+		}
+		response.Bids = append(response.Bids, bid)
+	}
+	writeJSON(w, http.StatusOK, &response)
+}
+
+// viewer returns the signed-in user, or a zero user for anonymous requests.
+// A session cookie that fails validation is rejected rather than ignored.
+func (h *Handler) viewer(r *http.Request) (types.User, *errors.AppError) {
+	if _, err := r.Cookie(auth.SessionCookie); err != nil {
+		return types.User{}, nil
+	}
+	return auth.UserFromRequest(r, h.db)
+}
+
+// visibleAuction loads an auction the viewer is allowed to see.
+func (h *Handler) visibleAuction(viewer types.User, auctionID string) (types.Auctions, *errors.AppError) {
+	auction, err := h.db.GetAuctionById(auctionID)
+	if err != nil {
+		log.Debugf("Unknown auction %s requested: %v", auctionID, err)
+		return types.Auctions{}, errors.New(errors.ErrAuctionNotFound, "Auction not found")
+	}
+
+	if appErr := bidding.CheckVisibility(auction, viewer.Role); appErr != nil {
+		return types.Auctions{}, appErr
+	}
+	return auction, nil
+}
+
+// redactAuction hides the reserve price, and the bidders other than the viewer.
+func redactAuction(auction types.Auctions, viewerID string) types.Auctions {
+	auction.ReservePrice = 0
+	if auction.CurrentBidderID != nil && *auction.CurrentBidderID != viewerID {
+		auction.CurrentBidderID = nil
+	}
+	if auction.WinnerID != nil && *auction.WinnerID != viewerID {
+		auction.WinnerID = nil
+	}
+	return auction
+}
+
+// parseAuctionFilter reads the filters and page of an auction listing.
+func parseAuctionFilter(r *http.Request) (database.AuctionFilter, *errors.AppError) {
+	query := r.URL.Query()
+	filter := database.AuctionFilter{
+		FuelType: query.Get("fuel_type"),
+		CarBody:  query.Get("car_body"),
+	}
+
+	for _, value := range query["status"] {
+		for _, status := range strings.Split(value, ",") {
+			status := types.AuctionStatus(strings.TrimSpace(status))
+			if !slices.Contains(bidding.ListedStatuses, status) {
+				return filter, errors.New(errors.ErrBadRequest, "Invalid status "+string(status))
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = bidding.ListedStatuses
+	}
+
+	if value := query.Get("only_for_merchants"); value != "" {
+		onlyForMerchants, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New(errors.ErrBadRequest, "Invalid only_for_merchants")
+		}
+		filter.OnlyForMerchants = &onlyForMerchants
+	}
+
+	var appErr *errors.AppError
+	if filter.EndsAfter, appErr = parseDate(r, "ends_after"); appErr != nil {
+		return filter, appErr
+	}
+	if filter.EndsBefore, appErr = parseDate(r, "ends_before"); appErr != nil {
+		return filter, appErr
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		endDate, id, ok := decodeCursor(cursor)
+		if !ok {
+			return filter, errors.New(errors.ErrBadRequest, "Invalid cursor")
+		}
+		filter.After = &database.AuctionCursor{EndDate: endDate, ID: id}
+	}
+
+	if filter.Limit, appErr = parseLimit(r); appErr != nil {
+		return filter, appErr
+	}
+	return filter, nil
+}
+
+// parseDate reads an optional RFC 3339 date from the query.
+func parseDate(r *http.Request, name string) (*time.Time, *errors.AppError) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.New(errors.ErrBadRequest, "Invalid "+name+", expected an RFC 3339 date")
+	}
+	return &date, nil
+}
+
+// parseLimit reads the page size of a listing.
+func parseLimit(r *http.Request) (int, *errors.AppError) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultPageSize, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxPageSize {
+		return 0, errors.New(errors.ErrBadRequest, "limit must be between 1 and "+strconv.Itoa(maxPageSize))
+	}
+	return limit, nil
+}
+
+// encodeCursor builds the opaque cursor of a listing position.
+func encodeCursor(at time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(at.UTC().Format(time.RFC3339Nano) + "|" + id))
+}
+
+// decodeCursor reads a cursor built by encodeCursor.
+func decodeCursor(cursor string) (time.Time, string, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	rawTime, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return time.Time{}, "", false
+	}
+	at, err := time.Parse(time.RFC3339Nano, rawTime)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return at, id, true
+}
+
+// encodeBidCursor builds the opaque cursor of a position in a bid listing.
+func encodeBidCursor(cursor database.BidCursor) string {
+	return encodeCursor(cursor.CreatedAt, strconv.Itoa(cursor.Price)+"|"+cursor.ID)
+}
+
+// decodeBidCursor reads a cursor built by encodeBidCursor.
+func decodeBidCursor(cursor string) (database.BidCursor, bool) {
+	createdAt, rest, ok := decodeCursor(cursor)
+	if !ok {
+		return database.BidCursor{}, false
+	}
+	rawPrice, id, ok := strings.Cut(rest, "|")
+	price, err := strconv.Atoi(rawPrice)
+	if !ok || err != nil || id == "" {
+		return database.BidCursor{}, false
+	}
+	return database.BidCursor{CreatedAt: createdAt, Price: price, ID: id}, true
+}
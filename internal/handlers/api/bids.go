@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Martin-Hayot/auction-server/internal/auth"
+	"github.com/Martin-Hayot/auction-server/internal/bidding"
+)
+
+// placeBidRequest is the body of a bid request.
+// The idempotency key may also be given with the Idempotency-Key header.
+type placeBidRequest struct {
+	Amount         int    `json:"amount"`
+	MaxAmount      int    `json:"max_amount,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// placeBidResponse is the body of a successful bid request.
+type placeBidResponse struct {
+	BidID      string `json:"bid_id"`
+	Amount     int    `json:"amount"`  // Current price after proxy bids were resolved
+	Leading    bool   `json:"leading"` // The bidder holds the current bid
+	MinimumBid int    `json:"minimum_bid"`
+	Duplicate  bool   `json:"duplicate,omitempty"` // The idempotency key was already used, nothing was placed
+}
+
+// HandlePlaceBid places a bid on an auction for the signed-in user, with the same rules
+// as bids placed over the WebSocket, and broadcasts it to live clients.
+// It answers 201 when the bid is placed and 200 when it was already placed with the same key.
+func (h *Handler) HandlePlaceBid(w http.ResponseWriter, r *http.Request) {
+	user, appErr := auth.UserFromRequest(r, h.db)
+	if appErr != nil {
+		writeError(w, appErr)
+		return
+	}
+
+	var req placeBidRequest
+	if appErr := decodeJSON(w, r, &req); appErr != nil {
+		writeError(w, appErr)
+		return
+	}
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = r.Header.Get("Idempotency-Key")
+	}
+
+	result, appErr := h.bids.PlaceBid(r.Context(), bidding.Bidder{ID: user.ID, Role: user.Role}, bidding.Bid{
+		AuctionID:      r.PathValue("id"),
+		Amount:         req.Amount,
+		MaxAmount:      req.MaxAmount,
+		IdempotencyKey: req.IdempotencyKey,
+	})
+	if appErr != nil {
+		writeError(w, appErr)
+		return
+	}
+
+	h.publisher.PublishBid(result)
+
+	status := http.StatusCreated
+	if result.Duplicate {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, &placeBidResponse{
+		BidID:      result.BidID,
+		Amount:     result.Auction.CurrentBid,
+		Leading:    result.Leading(user.ID),
+		MinimumBid: bidding.MinimumBid(result.Auction),
+		Duplicate:  result.Duplicate,
+	})
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Martin-Hayot/auction-server/internal/auth"
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+	"github.com/charmbracelet/log"
+)
+
+// myAuctionsResponse is the body of the signed-in user's portfolio.
+type myAuctionsResponse struct {
+	Auctions []types.ClientAuction `json:"auctions"`
+}
+
+// HandleMyAuctions lists the auctions the signed-in user has bid on, with their highest bid,
+// whether they are winning and how each auction turned out for them.
+func (h *Handler) HandleMyAuctions(w http.ResponseWriter, r *http.Request) {
+	user, appErr := auth.UserFromRequest(r, h.db)
+	if appErr != nil {
+		writeError(w, appErr)
+		return
+	}
+
+	clientAuctions, err := h.bids.Portfolio(user.ID)
+	if err != nil {
+		log.Error("Error getting client auctions: ", err)
+		writeError(w, errors.Internal(err))
+		return
+	}
+
+	response := myAuctionsResponse{Auctions: make([]types.ClientAuction, 0, len(clientAuctions))}
+	for _, clientAuction := range clientAuctions {
+		clientAuction.Auction = redactAuction(clientAuction.Auction, user.ID)
+		response.Auctions = append(response.Auctions, clientAuction)
+	}
+	writeJSON(w, http.StatusOK, &response)
+}
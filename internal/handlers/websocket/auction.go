@@ -3,10 +3,16 @@ package websocket
 import (
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Martin-Hayot/auction-server/configs"
 	"github.com/Martin-Hayot/auction-server/internal/auth"
+	"github.com/Martin-Hayot/auction-server/internal/bidding"
 	"github.com/Martin-Hayot/auction-server/internal/database"
+	"github.com/Martin-Hayot/auction-server/internal/lifecycle"
+	"github.com/Martin-Hayot/auction-server/internal/notify"
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
 	"github.com/Martin-Hayot/auction-server/pkg/types"
 	"github.com/charmbracelet/log"
 	"github.com/go-co-op/gocron"
@@ -17,8 +23,18 @@ import (
 // AuctionHandler handles WebSocket connections for the auction system.
 type AuctionHandler struct {
 	db               database.Service
-	connectedClients sync.Map   // Thread-safe map of connected clients.
-	clientLock       sync.Mutex // Mutex to synchronize access to connectedClients.
+	bids             *bidding.Service
+	tickInterval     time.Duration   // Interval of tick events in the final minute, 0 disables them
+	presenceInterval time.Duration   // Minimum interval between presence events of an auction
+	notifier         notify.Notifier // Reaches users who are not connected
+	heartbeat        Heartbeat
+	upgrader         websocket.Upgrader
+	queueSize        int           // Size of each client's send queue
+	queuePolicy      QueuePolicy   // What to do when a client's send queue is full
+	droppedMessages  atomic.Uint64 // Messages dropped by the queues of disconnected clients
+	clients          *Registry     // Connected clients by user.
+	rooms            *Rooms        // Clients subscribed to each auction.
+	events           *EventLog     // Recent events of each auction, for resuming clients.
 	CurrentAuctions  []types.Auctions
 	activeJobs       map[string]*AuctionJob
 	jobsMutex        sync.RWMutex
@@ -28,11 +44,18 @@ type AuctionJob struct {
 	timer             *time.Timer
 	auctionID         string
 	auctionEndDateUTC time.Time
+	onlyForMerchants  bool
 }
 
 func (h *AuctionHandler) StartPeriodicCheck() {
 	scheduler := gocron.NewScheduler(time.UTC)
 	scheduler.Every(1).Minute().Do(h.CheckAuctionsStatus)
+	if h.presenceInterval > 0 {
+		scheduler.Every(h.presenceInterval).Do(h.broadcastPresence)
+	}
+	if h.tickInterval > 0 {
+		scheduler.Every(h.tickInterval).Do(h.broadcastTicks)
+	}
 	scheduler.StartAsync()
 }
 
@@ -49,6 +72,23 @@ func (h *AuctionHandler) CheckAuctionsStatus() {
 	}
 
 	for _, auction := range auctions {
+		// Closed auctions need no timer
+		if lifecycle.IsFinal(auction.Status) {
+			continue
+		}
+
+		// Open scheduled auctions once their start date has passed
+		if auction.Status == types.StatusScheduled && !time.Now().Before(auction.StartDate) {
+			_, err = h.transitionAuction(auction.ID, func(types.Auctions) types.AuctionStatus {
+				return types.StatusLive
+			})
+			if err != nil {
+				log.Error("Error starting auction: ", err)
+				continue
+			}
+			log.Debugf("Auction %s is live", auction.ID)
+		}
+
 		// Check if job already exists
 		h.jobsMutex.RLock()
 		if _, exists := h.activeJobs[auction.ID]; exists {
@@ -66,14 +106,11 @@ func (h *AuctionHandler) CheckAuctionsStatus() {
 		job := &AuctionJob{
 			auctionID:         auctionID,
 			auctionEndDateUTC: auctionEndDateUTC,
+			onlyForMerchants:  auction.OnlyForMerchants,
 		}
 
 		if timeUntilEnd > 0 {
-			job.timer = time.AfterFunc(timeUntilEnd, func() {
-				log.Debugf("Auction %s ended", auctionID)
-				h.handleAuctionEnd(auctionID)
-				h.removeJob(auctionID)
-			})
+			job.timer = h.endTimer(auctionID, timeUntilEnd)
 			// Add these debug logs
 			log.Debugf("Auction %s: End date=%v, Timer will fire at=%v",
 				auctionID,
@@ -104,6 +141,34 @@ func (h *AuctionHandler) CheckAuctionsStatus() {
 	}
 }
 
+// endTimer starts the timer closing an auction after d.
+func (h *AuctionHandler) endTimer(auctionID string, d time.Duration) *time.Timer {
+	return time.AfterFunc(d, func() {
+		log.Debugf("Auction %s ended", auctionID)
+		// Drop the job first, closing arms a new one if a late bid extended the auction
+		h.removeJob(auctionID)
+		h.handleAuctionEnd(auctionID)
+	})
+}
+
+// rearmJob starts the end timer of an auction that is still running, unless one is already set.
+func (h *AuctionHandler) rearmJob(auction types.Auctions) {
+	h.jobsMutex.Lock()
+	defer h.jobsMutex.Unlock()
+	if _, exists := h.activeJobs[auction.ID]; exists {
+		return
+	}
+
+	endDate := auction.EndDate.UTC()
+	h.activeJobs[auction.ID] = &AuctionJob{
+		timer:             h.endTimer(auction.ID, time.Until(endDate)),
+		auctionID:         auction.ID,
+		auctionEndDateUTC: endDate,
+		onlyForMerchants:  auction.OnlyForMerchants,
+	}
+	log.Debugf("Auction %s rearmed to end at %v", auction.ID, endDate)
+}
+
 func (h *AuctionHandler) removeJob(auctionID string) {
 	h.jobsMutex.Lock()
 	defer h.jobsMutex.Unlock()
@@ -115,76 +180,98 @@ func (h *AuctionHandler) removeJob(auctionID string) {
 	}
 }
 
+// rescheduleJob moves the end timer of an auction to a new end date.
+// Auctions without a running timer are left to the periodic check, which reads the new end date.
+func (h *AuctionHandler) rescheduleJob(auctionID string, endDate time.Time) {
+	h.jobsMutex.Lock()
+	defer h.jobsMutex.Unlock()
+	job, exists := h.activeJobs[auctionID]
+	if !exists || job.timer == nil {
+		return
+	}
+
+	// The timer already fired, closing the auction rearms it from the new end date
+	if !job.timer.Stop() {
+		return
+	}
+	job.auctionEndDateUTC = endDate.UTC()
+	job.timer.Reset(time.Until(job.auctionEndDateUTC))
+	log.Debugf("Auction %s rescheduled to end at %v", auctionID, job.auctionEndDateUTC)
+}
+
 // NewAuctionWebSocketHandler creates a new instance of AuctionHandler.
-func NewAuctionWebSocketHandler(db database.Service) *AuctionHandler {
+func NewAuctionWebSocketHandler(db database.Service, bids *bidding.Service, cfg *configs.Config) *AuctionHandler {
 	return &AuctionHandler{
 		db:               db,
-		connectedClients: sync.Map{},
-		activeJobs:       make(map[string]*AuctionJob), // Initialize the map
-		jobsMutex:        sync.RWMutex{},
+		bids:             bids,
+		tickInterval:     configs.Duration(cfg.Auction.TickInterval, time.Second),
+		presenceInterval: configs.Duration(cfg.WebSocket.PresenceInterval, defaultPresenceInterval),
+		notifier:         notify.LogNotifier{},
+		heartbeat:        NewHeartbeat(cfg),
+		queueSize:        cfg.WebSocket.SendQueueSize,
+		queuePolicy:      ParseQueuePolicy(cfg.WebSocket.SendQueuePolicy),
+		upgrader: websocket.Upgrader{
+			CheckOrigin: newOriginChecker(cfg).Check,
+		},
+		clients:    NewRegistry(cfg.WebSocket.MaxConnectionsPerUser),
+		rooms:      NewRooms(),
+		events:     NewEventLog(replayBufferSize),
+		activeJobs: make(map[string]*AuctionJob), // Initialize the map
+		jobsMutex:  sync.RWMutex{},
 	}
 }
 
-var (
-	upgrader = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool { return true },
-	}
-)
+// SetNotifier replaces the notifier used to reach users who are not connected.
+func (h *AuctionHandler) SetNotifier(notifier notify.Notifier) {
+	h.notifier = notifier
+}
 
 // upgradeToWebSocket upgrades the HTTP request to a WebSocket connection and initializes a new client.
 // It adds the client to the list of connected clients and starts handling the client's messages.
 func (h *AuctionHandler) upgradeToWebSocket(w http.ResponseWriter, r *http.Request, user types.User) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	version, ok := negotiateVersion(r)
+	if !ok {
+		log.Debugf("Unsupported protocol version requested by %s", user.ID)
+		http.Error(w, errors.New(errors.ErrUnsupportedVersion, "Unsupported protocol version").ToJSON(), http.StatusBadRequest)
+		return
+	}
+
+	// Upgrade replies with an HTTP error itself when it fails
+	conn, err := h.upgrader.Upgrade(w, r, subprotocolHeader(r, version))
 	if err != nil {
 		log.Debugf("Failed to upgrade connection: %v", err)
-		http.Error(w, "Failed to establish connection", http.StatusInternalServerError)
 		return
 	}
 
 	// Initialize a new client
 	client := &Client{
-		ID:    user.ID,
-		Email: user.Email,
+		ID:      user.ID,
+		Email:   user.Email,
+		Role:    user.Role,
+		Version: version,
 		// Auctions:    ,
 		Conn:        conn,
-		Send:        make(chan []byte),
+		Heartbeat:   h.heartbeat,
+		Send:        NewSendQueue(h.queueSize, h.queuePolicy),
 		RateLimiter: rate.NewLimiter(1, 3),
 	}
 
 	// Add the client to the list of connected clients
-	h.clientLock.Lock()
-	h.connectedClients.Store(client, true)
-	h.clientLock.Unlock()
+	for _, evicted := range h.clients.Add(client) {
+		h.evict(evicted)
+	}
 
 	// Start handling the client
-	go client.ReadMessages(h.HandleMessage)
-	go client.WriteMessages()
+	go client.ReadMessages(h)
+	go client.WriteMessages(h)
 }
 
 // HandleAuctions handles incoming HTTP requests for the auction WebSocket.
 // It validates the user's token, retrieves the user from the database, and upgrades the connection to a WebSocket.
 func (h *AuctionHandler) HandleAuctions(w http.ResponseWriter, r *http.Request) {
-	// Validate the token from the cookie
-	token, err := auth.ValidateTokenFromCookie(r)
-	if err != nil || token == nil {
-		log.Debug("Invalid token: ", err)
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	var email string
-	err = token.Get("email", &email)
-	if err != nil {
-		log.Error("Error retrieving email from token claims", err)
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	// Check if the user exists
-	user, err := h.db.GetUserByEmail(email)
-	if err != nil {
-		log.Error("User not found: ", err)
-		http.Error(w, "User not found", http.StatusUnauthorized)
+	user, appErr := h.authenticate(r)
+	if appErr != nil {
+		http.Error(w, appErr.Message, http.StatusUnauthorized)
 		return
 	}
 
@@ -192,28 +279,51 @@ func (h *AuctionHandler) HandleAuctions(w http.ResponseWriter, r *http.Request)
 	h.upgradeToWebSocket(w, r, user)
 }
 
+// authenticate loads the user of the session cookie.
+func (h *AuctionHandler) authenticate(r *http.Request) (types.User, *errors.AppError) {
+	return auth.UserFromRequest(r, h.db)
+}
+
 // Broadcast sends a message to all connected clients.
 // It iterates over the connected clients and attempts to send the message to each client.
 func (h *AuctionHandler) Broadcast(message []byte) {
-	h.connectedClients.Range(func(key, value any) bool {
-		client := key.(*Client)
-
-		// Check if the client is closed
-		client.mu.Lock()
-		if client.closed {
-			client.mu.Unlock()
-			h.connectedClients.Delete(client) // Remove disconnected clients
-			return true
+	h.broadcastTo(message, func(*Client) bool { return true })
+}
+
+// BroadcastAuction sends a message about an auction to the clients subscribed to it.
+// Events of merchant-only auctions are only sent to merchants.
+func (h *AuctionHandler) BroadcastAuction(auction types.Auctions, message []byte) {
+	h.broadcastRoom(auction, message, KindEvent, auction.ID)
+}
+
+// broadcastRoom sends a message to the clients subscribed to an auction, merchants only
+// for merchant-only auctions. auctionID is set for sequenced events.
+func (h *AuctionHandler) broadcastRoom(auction types.Auctions, message []byte, kind MessageKind, auctionID string) {
+	for _, client := range h.rooms.Members(auction.ID) {
+		if auction.OnlyForMerchants && !client.IsMerchant() {
+			continue
 		}
-		client.mu.Unlock()
-
-		// Try to send the message
-		select {
-		case client.Send <- message:
-			// Message sent successfully
-		default:
-			client.Disconnect(h) // Disconnect the client on failure
+		h.sendTo(client, message, kind, auctionID)
+	}
+}
+
+// broadcastTo sends a message to the connected clients accepted by the filter.
+func (h *AuctionHandler) broadcastTo(message []byte, accept func(*Client) bool) {
+	for _, client := range h.clients.All() {
+		if accept(client) {
+			h.sendTo(client, message, KindLive, "")
 		}
-		return true // Continue iteration
-	})
+	}
+}
+
+// sendTo queues a message for a client without blocking. auctionID is set for auction events.
+// A full queue is handled by the queue policy. The queue of a disconnected client is closed,
+// such clients are removed. It returns false when the client is gone.
+func (h *AuctionHandler) sendTo(client *Client, message []byte, kind MessageKind, auctionID string) bool {
+	if client.enqueue(message, kind, auctionID) {
+		return true
+	}
+	h.clients.Remove(client) // Remove disconnected clients
+	h.rooms.LeaveAll(client)
+	return false
 }
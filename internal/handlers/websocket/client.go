@@ -2,7 +2,9 @@ package websocket
 
 import (
 	"sync"
+	"time"
 
+	"github.com/Martin-Hayot/auction-server/internal/bidding"
 	"github.com/charmbracelet/log"
 	"github.com/gorilla/websocket"
 	"golang.org/x/time/rate"
@@ -11,67 +13,142 @@ import (
 type Client struct {
 	ID          string
 	Email       string
+	Role        string
+	Anonymous   bool // Watching without being signed in, cannot bid. The WebSocket requires a session, only read-only transports set it
+	Version     int  // Negotiated protocol version
 	Auctions    []string
 	Conn        *websocket.Conn
-	Send        chan []byte   // Channel for outgoing messages
+	Heartbeat   Heartbeat     // Timings used to detect a dead connection
+	Send        *SendQueue    // Queue of outgoing messages
 	RateLimiter *rate.Limiter // Rate limiter to prevent spamming
 	closed      bool          // Flag to check if the connection is closed
-	mu          sync.Mutex    // Mutex to protect the closed flag
+	mu          sync.Mutex    // Mutex to protect the closed flag and auctions
+}
+
+// IsMerchant reports whether the client's user has the merchant role.
+func (c *Client) IsMerchant() bool {
+	return bidding.IsMerchant(c.Role)
 }
 
 // readMessages listens for incoming messages from the client.
-func (c *Client) ReadMessages(handleMessage func(*Client, []byte)) {
+func (c *Client) ReadMessages(handler *AuctionHandler) {
 	defer func() {
-		c.Disconnect(nil) // Ensure cleanup
+		c.Disconnect(handler) // Ensure cleanup
 		log.Debugf("Connection closed for client %s", c.ID)
 	}()
 
+	// Any message, pongs included, proves the client is still there
+	c.Conn.SetReadLimit(c.Heartbeat.MaxMessageSize)
+	c.Conn.SetReadDeadline(time.Now().Add(c.Heartbeat.PongTimeout))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(c.Heartbeat.PongTimeout))
+	})
+
 	for {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
-			log.Debugf("Error reading message from client %s: %v", c.ID, err)
+			if isTimeout(err) {
+				log.Debugf("Client %s missed its heartbeat, evicting it", c.ID)
+			} else {
+				log.Debugf("Error reading message from client %s: %v", c.ID, err)
+			}
 			break
 		}
-		handleMessage(c, message)
+		c.Conn.SetReadDeadline(time.Now().Add(c.Heartbeat.PongTimeout))
+		handler.HandleMessage(c, message)
 	}
 }
 
-// writeMessages sends outgoing messages to the client.
-func (c *Client) WriteMessages() {
+// writeMessages sends outgoing messages to the client, and pings it
+// at the heartbeat interval.
+func (c *Client) WriteMessages(handler *AuctionHandler) {
+	ticker := time.NewTicker(c.Heartbeat.PingInterval)
 	defer func() {
+		ticker.Stop()
 		c.Conn.Close()
 	}()
 
-	for message := range c.Send {
-		c.mu.Lock()
-		if c.closed {
-			c.mu.Unlock()
-			return
+	for {
+		select {
+		case <-c.Send.Ready():
+			for {
+				message, snapshotOf, ok := c.Send.Pop()
+				if !ok {
+					break
+				}
+				if snapshotOf != "" {
+					if message, ok = handler.snapshotEvent(c, snapshotOf); !ok {
+						continue
+					}
+				}
+				if err := c.write(websocket.TextMessage, message); err != nil {
+					log.Debugf("Error sending message to client %s: %v", c.ID, err)
+					return
+				}
+			}
+			if c.Send.Closed() {
+				return
+			}
+		case <-ticker.C:
+			if err := c.write(websocket.PingMessage, nil); err != nil {
+				log.Debugf("Error pinging client %s: %v", c.ID, err)
+				return
+			}
 		}
+	}
+}
+
+// write sends a single frame to the client within the write timeout.
+// Only the write loop writes frames, so the lock is not held during the write
+// and a slow socket does not block the broadcasts or rooms waiting on it.
+func (c *Client) write(messageType int, data []byte) error {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return websocket.ErrCloseSent
+	}
 
-		err := c.Conn.WriteMessage(websocket.TextMessage, message)
-		c.mu.Unlock()
+	c.Conn.SetWriteDeadline(time.Now().Add(c.Heartbeat.WriteTimeout))
+	return c.Conn.WriteMessage(messageType, data)
+}
 
-		if err != nil {
-			log.Debugf("Error sending message to client %s: %v", c.ID, err)
-			return
-		}
+// enqueue queues a message for the client without blocking. auctionID is set for auction events.
+// When the queue overflows and gets closed, the write loop closes the connection,
+// which ends the read loop and cleans the client up.
+// It returns false when the queue is closed.
+func (c *Client) enqueue(message []byte, kind MessageKind, auctionID string) bool {
+	if !c.Send.Push(message, kind, auctionID) {
+		_, dropped := c.Send.Stats()
+		log.Debugf("Send queue of client %s closed, %d messages dropped", c.ID, dropped)
+		return false
 	}
+	return true
 }
 
 // Disconnect cleans up client resources.
 func (c *Client) Disconnect(handler *AuctionHandler) {
 	c.mu.Lock()
-	if !c.closed {
+	closing := !c.closed
+	if closing {
 		c.closed = true
-		close(c.Send)
+		c.Send.Close()
 	}
 	c.mu.Unlock()
 
 	if handler != nil {
-		handler.connectedClients.Delete(c)
+		// Keep the messages the client missed in the totals once it is gone
+		if closing {
+			_, dropped := c.Send.Stats()
+			handler.droppedMessages.Add(dropped)
+		}
+		handler.clients.Remove(c)
+		handler.rooms.LeaveAll(c)
 	}
 
-	c.Conn.Close()
+	// Clients streaming over SSE have no socket, their handler returns instead
+	if c.Conn != nil {
+		c.Conn.Close()
+	}
 	log.Debugf("Client %s cleanup completed", c.ID) // Lower-level log here
 }
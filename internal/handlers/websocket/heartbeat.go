@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"net"
+	"time"
+
+	"github.com/Martin-Hayot/auction-server/configs"
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/charmbracelet/log"
+)
+
+// Heartbeat defaults, used when the configuration leaves a setting empty.
+const (
+	defaultPingInterval   = 30 * time.Second
+	defaultWriteTimeout   = 10 * time.Second
+	defaultMaxMessageSize = 1024
+)
+
+// Heartbeat holds the timings used to detect dead connections.
+// The server pings every PingInterval and drops clients that have not
+// answered with a pong, or sent anything else, within PongTimeout.
+type Heartbeat struct {
+	PingInterval   time.Duration
+	PongTimeout    time.Duration
+	WriteTimeout   time.Duration // Deadline for a single write to the client
+	MaxMessageSize int64         // Largest inbound message accepted, in bytes
+}
+
+// NewHeartbeat reads the heartbeat timings from the configuration.
+// The pong timeout defaults to twice the ping interval and is raised to it
+// when configured shorter, so a live client always gets a chance to answer.
+func NewHeartbeat(cfg *configs.Config) Heartbeat {
+	heartbeat := Heartbeat{
+		PingInterval:   configs.Duration(cfg.WebSocket.PingInterval, defaultPingInterval),
+		WriteTimeout:   configs.Duration(cfg.WebSocket.WriteTimeout, defaultWriteTimeout),
+		MaxMessageSize: int64(cfg.WebSocket.MaxMessageSize),
+	}
+	if heartbeat.PingInterval <= 0 {
+		heartbeat.PingInterval = defaultPingInterval
+	}
+	if heartbeat.MaxMessageSize <= 0 {
+		heartbeat.MaxMessageSize = defaultMaxMessageSize
+	}
+
+	heartbeat.PongTimeout = configs.Duration(cfg.WebSocket.PongTimeout, 2*heartbeat.PingInterval)
+	if heartbeat.PongTimeout <= heartbeat.PingInterval {
+		log.Warnf("Pong timeout %v is not longer than the ping interval, using %v",
+			heartbeat.PongTimeout, 2*heartbeat.PingInterval)
+		heartbeat.PongTimeout = 2 * heartbeat.PingInterval
+	}
+	return heartbeat
+}
+
+// isTimeout reports whether a connection error comes from a missed deadline.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
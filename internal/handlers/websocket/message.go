@@ -2,158 +2,264 @@ package websocket
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
+	"time"
 
+	"github.com/Martin-Hayot/auction-server/internal/bidding"
+	"github.com/Martin-Hayot/auction-server/internal/lifecycle"
+	"github.com/Martin-Hayot/auction-server/internal/notify"
 	"github.com/Martin-Hayot/auction-server/pkg/errors"
 	"github.com/Martin-Hayot/auction-server/pkg/types"
 	"github.com/charmbracelet/log"
 )
 
-type Message struct {
-	Type string `json:"type"` // Type of the message (e.g., "bid", "update")
-	Data string `json:"data"` // Payload of the message
-}
-
-// ParseMessage validates and parses incoming messages.
-func ParseMessage(rawMessage []byte) (*Message, error) {
-	var msg Message
-	err := json.Unmarshal(rawMessage, &msg)
+// HandleMessage routes the message based on its type.
+// Every message gets exactly one "ack" or "nack" reply carrying its ID.
+func (h *AuctionHandler) HandleMessage(client *Client, rawMessage []byte) {
+	receivedAt := time.Now()
+	msg, err := ParseMessage(rawMessage)
 	if err != nil {
-		return nil, err
+		log.Infof("Invalid message from client %s: %v", client.ID, err)
+		client.nack(nil, errors.New(errors.ErrBadMessageFormat, "Invalid message format"))
+		return
 	}
-	return &msg, nil
-}
 
-// HandleMessage routes the message based on its type.
-func (h *AuctionHandler) HandleMessage(client *Client, rawMessage []byte) {
-	if !client.RateLimiter.Allow() {
-		log.Warnf("Rate limit exceeded for client %s", client.ID)
-		client.Send <- []byte(`{"type": "error", "message": "Rate limit exceeded"}`)
+	if msg.ID == "" {
+		client.nack(msg, errors.New(errors.ErrBadMessageFormat, "Missing message id"))
 		return
 	}
 
-	msg, err := ParseMessage(rawMessage)
-	if err != nil {
-		log.Infof("Invalid message from client %s: %v", client.ID, err)
-		client.Send <- []byte(errors.New(errors.ErrBadMessageFormat, "Invalid message format").ToJSON())
+	// The version is negotiated at connect time, a message may only restate it
+	if msg.Version != 0 && msg.Version != client.Version {
+		client.nack(msg, errors.New(errors.ErrUnsupportedVersion, "Message version differs from the negotiated protocol version"))
+		return
+	}
+
+	if !client.RateLimiter.Allow() {
+		log.Warnf("Rate limit exceeded for client %s", client.ID)
+		client.nack(msg, errors.New(errors.ErrRateLimited, "Rate limit exceeded"))
 		return
 	}
 
 	switch msg.Type {
-	case "join":
-		log.Debug("Client joined the auction")
-	case "bid":
-		h.handleBidMessage(client, msg.Data)
-	case "update":
-		log.Debug("Client requested an update")
+	case TypeJoin:
+		h.handleJoinMessage(client, msg)
+	case TypeLeave:
+		h.handleLeaveMessage(client, msg)
+	case TypeBid:
+		h.handleBidMessage(client, msg)
+	case TypeUpdate:
+		h.handleUpdateMessage(client, msg)
+	case TypeResume:
+		h.handleResumeMessage(client, msg)
+	case TypeTimeSync:
+		h.handleTimeSyncMessage(client, msg, receivedAt)
+	case TypeMyAuctions:
+		h.handleMyAuctionsMessage(client, msg)
 	default:
 		log.Printf("Unknown message type: %s", msg.Type)
-		client.Send <- []byte(errors.New(errors.ErrUnknownMessageType, "Unknown message type").ToJSON())
+		client.nack(msg, errors.New(errors.ErrUnknownMessageType, "Unknown message type"))
 	}
 }
 
 // Handlers for specific message types
-func (h *AuctionHandler) handleBidMessage(client *Client, data string) {
-	type BidMessage struct {
-		AuctionID string `json:"auction_id"`
-		Amount    int    `json:"amount"`
-	}
-	var bidMsg BidMessage
+func (h *AuctionHandler) handleBidMessage(client *Client, msg *Message) {
+	var bidMsg BidPayload
 
-	err := json.Unmarshal([]byte(data), &bidMsg)
-	if err != nil {
-		client.Send <- []byte(errors.New(errors.ErrBadMessageFormat, "Invalid bid message").ToJSON())
+	err := msg.DecodePayload(&bidMsg)
+	if err != nil || msg.AuctionID == "" {
+		client.nack(msg, errors.New(errors.ErrBadMessageFormat, "Invalid bid message"))
 		return
 	}
 
-	ctx := context.Background()
-	tx, err := h.db.BeginTx(ctx)
-	if err != nil {
-		log.Error("Error starting transaction: ", err)
-		client.Send <- []byte(errors.New(errors.ErrInternalServer, "Internal server error").ToJSON())
+	result, appErr := h.bids.PlaceBid(context.Background(), bidding.Bidder{ID: client.ID, Role: client.Role}, bidding.Bid{
+		AuctionID:      msg.AuctionID,
+		Amount:         bidMsg.Amount,
+		MaxAmount:      bidMsg.MaxAmount,
+		IdempotencyKey: bidMsg.IdempotencyKey,
+	})
+	if appErr != nil {
+		client.nack(msg, appErr)
 		return
 	}
-	defer func() {
-		if p := recover(); p != nil {
-			tx.Rollback()
-			panic(p)
-		} else if err != nil {
-			tx.Rollback()
-		} else {
-			tx.Commit()
-		}
-	}()
 
-	auction, err := h.db.GetAuctionByIdTx(ctx, tx, bidMsg.AuctionID)
+	client.ack(msg, &BidAck{
+		BidID:      result.BidID,
+		Amount:     result.Auction.CurrentBid,
+		Leading:    result.Leading(client.ID),
+		MinimumBid: bidding.MinimumBid(result.Auction),
+		Duplicate:  result.Duplicate,
+	})
+
+	h.PublishBid(result)
+}
+
+// handleMyAuctionsMessage replies with the auctions the client has bid on.
+func (h *AuctionHandler) handleMyAuctionsMessage(client *Client, msg *Message) {
+	clientAuctions, err := h.bids.Portfolio(client.ID)
 	if err != nil {
-		log.Error("Error retrieving auction: ", err)
-		client.Send <- []byte(errors.New(errors.ErrInternalServer, "Internal server error").ToJSON())
+		log.Error("Error getting client auctions: ", err)
+		client.nack(msg, errors.Internal(err))
 		return
 	}
 
-	if bidMsg.Amount <= auction.CurrentBid {
-		client.Send <- []byte(errors.New(errors.ErrBidTooLow, "Bid amount must be higher than current price").ToJSON())
-		return
+	ack := MyAuctionsAck{Auctions: make([]MyAuction, 0, len(clientAuctions))}
+	for _, clientAuction := range clientAuctions {
+		ack.Auctions = append(ack.Auctions, MyAuction{
+			AuctionID:  clientAuction.Auction.ID,
+			Status:     clientAuction.Auction.Status,
+			EndDate:    clientAuction.Auction.EndDate.UTC(),
+			CurrentBid: clientAuction.Auction.CurrentBid,
+			HighestBid: clientAuction.HighestBid,
+			Winning:    clientAuction.Winning,
+			Outcome:    clientAuction.Outcome,
+		})
 	}
+	client.ack(msg, &ack)
+}
 
-	// Update auction
-	auction.CurrentBid = bidMsg.Amount
-	auction.CurrentBidderID = &client.ID
-	auction.BiddersCount++
-	auction, err = h.db.UpdateAuctionByIdTx(ctx, tx, auction)
-	if err != nil {
-		log.Error("Error updating auction: ", err)
+// PublishBid broadcasts a committed bid and its consequences to the auction's subscribers,
+// never revealing the maximums. Every transport taking bids calls it once the bid is placed.
+func (h *AuctionHandler) PublishBid(result bidding.Result) {
+	if result.Duplicate {
 		return
 	}
 
-	// Create new bid
-	bid := types.Bid{
-		AuctionID: auction.ID,
-		UserID:    client.ID,
-		Price:     bidMsg.Amount,
+	auction := result.Auction
+	for _, placement := range result.Placements {
+		h.publish(auction, TypeBidPlaced, &BidEvent{
+			Amount: placement.Price,
+			Auto:   placement.Auto,
+		})
 	}
-	_, err = h.db.CreateBidTx(ctx, tx, bid)
-	if err != nil {
-		log.Error("Error creating bid: ", err)
-		return
+
+	if result.Outbid != "" {
+		h.notifyOutbid(result.Outbid, auction)
 	}
 
-	// Broadcast bid to all clients
-	rawMessage, err := json.Marshal(&Message{Type: "bid", Data: data})
-	if err != nil {
-		log.Error("Error marshalling bid message: ", err)
-		return
+	if result.Extended {
+		log.Debugf("Auction %s extended to %v", auction.ID, auction.EndDate)
+		h.rescheduleJob(auction.ID, auction.EndDate)
+		h.broadcastAuctionExtended(auction)
+	}
+
+	if result.Sold {
+		log.Debugf("Auction %s sold at maximum price %d", auction.ID, auction.CurrentBid)
+		h.removeJob(auction.ID)
+		h.broadcastAuctionEnd(auction)
 	}
-	h.Broadcast(rawMessage)
 }
 
 func (h *AuctionHandler) handleAuctionEnd(auctionID string) {
 	// Process auction end
 	log.Debugf("Auction %s has ended", auctionID)
 
-	// designate winner
-	auction, err := h.db.GetAuctionById(auctionID)
-
+	// designate winner, unless a late bid pushed the end date back after the timer fired
+	closed := false
+	auction, err := h.transitionAuction(auctionID, func(auction types.Auctions) types.AuctionStatus {
+		closed = false
+		if auction.Status != types.StatusLive && auction.Status != types.StatusPaused {
+			return auction.Status
+		}
+		if time.Now().Before(auction.EndDate) {
+			return auction.Status
+		}
+		closed = true
+		return lifecycle.Outcome(auction)
+	})
 	if err != nil {
-		log.Error("Error retrieving auction: ", err)
+		log.Error("Error closing auction: ", err)
+		return
+	}
+
+	if !closed {
+		// Only a running auction that was extended gets a new timer, the periodic check
+		// takes care of the others, such as auctions not opened yet
+		running := auction.Status == types.StatusLive || auction.Status == types.StatusPaused
+		if running && time.Now().Before(auction.EndDate) {
+			log.Debugf("Auction %s was extended to %v", auctionID, auction.EndDate)
+			h.rearmJob(auction)
+		}
 		return
 	}
 
-	if auction.CurrentBid < auction.ReservePrice {
-		log.Debug("Auction did not meet reserve price")
-		auction.Status = "reserve_not_met"
-	} else {
-		log.Debug("Auction met reserve price")
-		auction.Status = "sold"
-		auction.WinnerID = auction.CurrentBidderID
+	log.Debugf("Auction %s closed as %s", auctionID, auction.Status)
+
+	h.broadcastAuctionEnd(auction)
+}
 
-		// Update auction in database
-		_, err = h.db.UpdateAuctionById(auction)
+// transitionAuction moves an auction to the status chosen by next and persists it atomically.
+// Sold auctions get the current bidder as winner. The auction is left untouched when next
+// returns its current status.
+func (h *AuctionHandler) transitionAuction(auctionID string, next func(types.Auctions) types.AuctionStatus) (types.Auctions, error) {
+	ctx := context.Background()
+	var auction types.Auctions
+	err := h.db.RunInTx(ctx, func(tx *sql.Tx) error {
+		current, err := h.db.GetAuctionByIdTx(ctx, tx, auctionID)
 		if err != nil {
-			log.Error("Error updating auction: ", err)
-			return
+			return err
+		}
+
+		from := current.Status
+		to := next(current)
+		if to == from {
+			auction = current
+			return nil
+		}
+		if appErr := lifecycle.Transition(&current, to); appErr != nil {
+			return appErr
+		}
+		if current.Status == types.StatusSold {
+			current.WinnerID = current.CurrentBidderID
+		}
+
+		auction, err = h.db.UpdateAuctionStatusTx(ctx, tx, current, from)
+		return err
+	})
+	if err != nil {
+		return types.Auctions{}, err
+	}
+	return auction, nil
+}
+
+// notifyOutbid tells a bidder they lost the lead on an auction, on every device they are
+// connected with, or through the notifier when they are offline.
+func (h *AuctionHandler) notifyOutbid(userID string, auction types.Auctions) {
+	rawMessage, err := NewEvent(TypeOutbid, auction.ID, 0, &OutbidEvent{Amount: auction.CurrentBid})
+	if err != nil {
+		log.Error("Error marshalling outbid message: ", err)
+		return
+	}
+	if h.SendToUser(userID, rawMessage) > 0 {
+		return
+	}
+
+	event := notify.Outbid{UserID: userID, AuctionID: auction.ID, Price: auction.CurrentBid}
+	go func() {
+		if err := h.notifier.NotifyOutbid(context.Background(), event); err != nil {
+			log.Errorf("Error notifying user %s of outbid: %v", userID, err)
 		}
+	}()
+}
+
+// broadcastAuctionExtended notifies clients of the new end date of an auction.
+func (h *AuctionHandler) broadcastAuctionExtended(auction types.Auctions) {
+	h.publish(auction, TypeAuctionExtended, &AuctionExtendedEvent{
+		EndDate: auction.EndDate.UTC(),
+	})
+}
+
+// broadcastAuctionEnd notifies clients that an auction has closed.
+func (h *AuctionHandler) broadcastAuctionEnd(auction types.Auctions) {
+	event := AuctionEndEvent{
+		Status: auction.Status,
 	}
+	if auction.Status == types.StatusSold {
+		event.WinningPrice = auction.CurrentBid
+	}
+
+	h.publish(auction, TypeAuctionEnd, &event)
 
-	h.Broadcast([]byte(`{"type": "auction_end", "data": "Auction has ended"}`))
+	// Nothing follows the end of an auction, resuming clients get a snapshot
+	h.events.Forget(auction.ID)
 }
@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Martin-Hayot/auction-server/configs"
+	"github.com/charmbracelet/log"
+)
+
+// originChecker decides which origins may open a WebSocket.
+// Browsers always send the Origin header on WebSocket handshakes, so checking it
+// keeps other sites from opening sockets with the user's auth cookie.
+type originChecker struct {
+	allowAll bool
+	allowed  map[string]struct{} // Normalized "scheme://host[:port]" origins
+}
+
+// newOriginChecker builds the origin policy from the configuration.
+// Features.AllowCrossOrigin accepts any origin and is meant for development.
+func newOriginChecker(cfg *configs.Config) *originChecker {
+	checker := &originChecker{
+		allowAll: cfg.Features.AllowCrossOrigin,
+		allowed:  make(map[string]struct{}),
+	}
+	for _, origin := range cfg.WebSocket.AllowedOrigins {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+		normalized, ok := normalizeOrigin(origin)
+		if !ok {
+			log.Warnf("Ignoring invalid allowed origin %q", origin)
+			continue
+		}
+		checker.allowed[normalized] = struct{}{}
+	}
+	if checker.allowAll {
+		log.Warn("Cross-origin WebSocket connections are allowed from any origin")
+	}
+	return checker
+}
+
+// Check accepts requests without an Origin header (non-browser clients), from the
+// same host as the server, or from one of the allowed origins.
+func (o *originChecker) Check(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || o.allowAll {
+		return true
+	}
+
+	normalized, ok := normalizeOrigin(origin)
+	if ok {
+		if _, allowed := o.allowed[normalized]; allowed {
+			return true
+		}
+		if u, _ := url.Parse(normalized); strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+	}
+
+	log.Warnf("Rejected WebSocket upgrade from origin %q (remote %s)", origin, r.RemoteAddr)
+	return false
+}
+
+// normalizeOrigin lowercases the scheme and host of an origin and drops anything after them.
+func normalizeOrigin(origin string) (string, bool) {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", false
+	}
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host), true
+}
@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// defaultPresenceInterval bounds how often presence events are sent for an auction when not configured.
+const defaultPresenceInterval = 2 * time.Second
+
+// Presence is the number of people watching an auction. Authenticated users are
+// counted once however many connections they have, anonymous watchers per connection.
+type Presence struct {
+	Authenticated int `json:"authenticated"`
+	Anonymous     int `json:"anonymous"`
+}
+
+// Total returns the number of watchers.
+func (p Presence) Total() int {
+	return p.Authenticated + p.Anonymous
+}
+
+// Presence counts the watchers of an auction.
+func (r *Rooms) Presence(auctionID string) Presence {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var presence Presence
+	users := make(map[string]struct{})
+	for client := range r.members[auctionID] {
+		if client.Anonymous {
+			presence.Anonymous++
+			continue
+		}
+		users[client.ID] = struct{}{}
+	}
+	presence.Authenticated = len(users)
+	return presence
+}
+
+// Watchers returns the watchers of an auction, for the admin dashboard.
+func (h *AuctionHandler) Watchers(auctionID string) Presence {
+	return h.rooms.Presence(auctionID)
+}
+
+// broadcastPresence sends the watcher counts of the auctions whose rooms changed
+// since the last call. It runs every presence interval, which throttles the events
+// of busy auctions to one per interval.
+func (h *AuctionHandler) broadcastPresence() {
+	for _, auctionID := range h.rooms.TakeChanged() {
+		presence := h.rooms.Presence(auctionID)
+		if presence.Total() == 0 {
+			continue
+		}
+
+		// Presence is only meaningful live, it is sent without a sequence number and
+		// queued as a live message, so a full queue never merges it with auction events
+		rawMessage, err := NewEvent(TypePresence, auctionID, 0, &PresenceEvent{
+			Watchers:      presence.Total(),
+			Authenticated: presence.Authenticated,
+			Anonymous:     presence.Anonymous,
+		})
+		if err != nil {
+			log.Error("Error marshalling presence message: ", err)
+			continue
+		}
+
+		// Only clients allowed to see the auction could join its room
+		for _, client := range h.rooms.Members(auctionID) {
+			h.sendTo(client, rawMessage, KindLive, "")
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+	"github.com/charmbracelet/log"
+	"github.com/gorilla/websocket"
+)
+
+// ProtocolVersion is the latest version of the WebSocket protocol.
+// Clients pick a version at connect time with the "auction.v<N>" subprotocol
+// or the "version" query parameter, and get the latest one otherwise.
+const ProtocolVersion = 1
+
+// subprotocolPrefix prefixes the version in the Sec-WebSocket-Protocol header.
+const subprotocolPrefix = "auction.v"
+
+// supportedVersions lists the protocol versions the server can speak, latest first.
+var supportedVersions = []int{1}
+
+// Message types
+const (
+	// Inbound
+	TypeJoin       = "join"
+	TypeLeave      = "leave"
+	TypeBid        = "bid"
+	TypeUpdate     = "update"
+	TypeResume     = "resume"
+	TypeTimeSync   = "time_sync"
+	TypeMyAuctions = "my_auctions"
+
+	// Replies
+	TypeAck  = "ack"
+	TypeNack = "nack"
+
+	// Events
+	TypeBidPlaced       = "bid"
+	TypeAuctionExtended = "auction_extended"
+	TypeAuctionEnd      = "auction_end"
+	TypeOutbid          = "outbid"
+	TypeTick            = "tick"
+	TypePresence        = "presence"
+	TypeSnapshot        = "snapshot"
+)
+
+// Message is the envelope of every message exchanged over the WebSocket.
+type Message struct {
+	Type      string          `json:"type"`                 // Type of the message (e.g., "bid", "update")
+	Version   int             `json:"version,omitempty"`    // Protocol version, must match the negotiated one when set
+	ID        string          `json:"id,omitempty"`         // Correlation ID chosen by the client, echoed in replies
+	AuctionID string          `json:"auction_id,omitempty"` // Auction the message is about
+	Seq       uint64          `json:"seq,omitempty"`        // Sequence number of an auction event
+	Payload   json.RawMessage `json:"payload,omitempty"`    // Type-specific payload
+}
+
+// BidPayload is the payload of an inbound "bid" message.
+// MaxAmount is optional; when set, the server keeps it secret and raises the
+// bid on the client's behalf up to that amount whenever someone else bids.
+// IdempotencyKey is optional; a bid resubmitted with a key the bidder already
+// used is not placed again and gets the original bid back.
+type BidPayload struct {
+	Amount         int    `json:"amount"`
+	MaxAmount      int    `json:"max_amount,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// ResumePayload is the payload of a "resume" message.
+type ResumePayload struct {
+	LastSeq uint64 `json:"last_seq"` // Sequence number of the last event the client saw
+}
+
+// TimeSyncPayload is the payload of a "time_sync" message.
+// Timestamps are Unix times in milliseconds.
+type TimeSyncPayload struct {
+	ClientSentAt int64 `json:"client_sent_at"`
+}
+
+// BidAck is the payload of the "ack" reply to a "bid" message.
+type BidAck struct {
+	BidID      string `json:"bid_id"`
+	Amount     int    `json:"amount"`  // Current price after proxy bids were resolved
+	Leading    bool   `json:"leading"` // The bidder holds the current bid
+	MinimumBid int    `json:"minimum_bid"`
+	Duplicate  bool   `json:"duplicate,omitempty"` // The idempotency key was already used, nothing was placed
+}
+
+// ResumeAck is the payload of the "ack" reply to a "resume" message.
+// Snapshot is only set when the missed events could not be replayed.
+type ResumeAck struct {
+	Replayed int              `json:"replayed"`
+	Snapshot *AuctionSnapshot `json:"snapshot,omitempty"`
+}
+
+// TimeSyncAck is the payload of the "ack" reply to a "time_sync" message.
+// Timestamps are Unix times in milliseconds.
+type TimeSyncAck struct {
+	ClientSentAt     int64 `json:"client_sent_at"`     // Echoed from the request
+	ServerReceivedAt int64 `json:"server_received_at"` // When the request was read
+	ServerSentAt     int64 `json:"server_sent_at"`     // When the reply was sent
+}
+
+// MyAuctionsAck is the payload of the "ack" reply to a "my_auctions" message.
+type MyAuctionsAck struct {
+	Auctions []MyAuction `json:"auctions"`
+}
+
+// MyAuction is an auction the client has bid on, without the identity of other bidders.
+type MyAuction struct {
+	AuctionID  string              `json:"auction_id"`
+	Status     types.AuctionStatus `json:"status"`
+	EndDate    time.Time           `json:"end_date"`
+	CurrentBid int                 `json:"current_bid"`
+	HighestBid int                 `json:"highest_bid"` // Highest bid of the client
+	Winning    bool                `json:"winning"`     // The client holds the current bid
+	Outcome    types.BidderOutcome `json:"outcome"`
+}
+
+// BidEvent is the payload broadcast for every bid recorded on an auction.
+type BidEvent struct {
+	Amount int  `json:"amount"`
+	Auto   bool `json:"auto"` // Placed by the proxy bidding engine
+}
+
+// OutbidEvent is the payload sent to every connection of a bidder who lost the lead.
+type OutbidEvent struct {
+	Amount int `json:"amount"` // Current price after the new bid
+}
+
+// AuctionExtendedEvent is the payload broadcast when a late bid extends an auction.
+type AuctionExtendedEvent struct {
+	EndDate time.Time `json:"end_date"`
+}
+
+// TickEvent is the payload sent every tick interval during the final minute of an auction.
+type TickEvent struct {
+	EndDate         time.Time `json:"end_date"`
+	ServerTime      time.Time `json:"server_time"`
+	TimeRemainingMs int64     `json:"time_remaining_ms"`
+}
+
+// PresenceEvent is the payload sent to the watchers of an auction when their number changes.
+type PresenceEvent struct {
+	Watchers      int `json:"watchers"`
+	Authenticated int `json:"authenticated"`
+	Anonymous     int `json:"anonymous"`
+}
+
+// AuctionEndEvent is the payload broadcast when an auction closes.
+type AuctionEndEvent struct {
+	Status       types.AuctionStatus `json:"status"`
+	WinningPrice int                 `json:"winning_price,omitempty"`
+}
+
+// AuctionSnapshot is the payload of the "ack" reply to "join" and "update" messages.
+// The reserve price itself is never sent, only whether it has been met.
+type AuctionSnapshot struct {
+	Seq             uint64              `json:"seq"` // Sequence number of the latest event, to resume from
+	Status          types.AuctionStatus `json:"status"`
+	CurrentBid      int                 `json:"current_bid"`
+	MinimumBid      int                 `json:"minimum_bid"`
+	BiddersCount    int                 `json:"bidders_count"`
+	ReserveMet      bool                `json:"reserve_met"`
+	Leading         bool                `json:"leading"` // The client holds the current bid
+	EndDate         time.Time           `json:"end_date"`
+	ServerTime      time.Time           `json:"server_time"`
+	TimeRemainingMs int64               `json:"time_remaining_ms"`
+	RecentBids      []RecentBid         `json:"recent_bids"`
+}
+
+// RecentBid is a bid as shown in a snapshot, without the identity of other bidders.
+type RecentBid struct {
+	Amount   int       `json:"amount"`
+	PlacedAt time.Time `json:"placed_at"`
+	Mine     bool      `json:"mine"`
+}
+
+// NackPayload is the payload of a "nack" reply.
+type NackPayload struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ParseMessage validates and parses incoming messages.
+func ParseMessage(rawMessage []byte) (*Message, error) {
+	var msg Message
+	err := json.Unmarshal(rawMessage, &msg)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Type == "" {
+		return nil, fmt.Errorf("missing message type")
+	}
+	return &msg, nil
+}
+
+// DecodePayload unmarshals the payload of a message into v.
+func (m *Message) DecodePayload(v any) error {
+	if len(m.Payload) == 0 {
+		return fmt.Errorf("missing payload")
+	}
+	return json.Unmarshal(m.Payload, v)
+}
+
+// NewEvent encodes an auction event with the latest protocol version.
+func NewEvent(msgType, auctionID string, seq uint64, payload any) ([]byte, error) {
+	msg := Message{
+		Type:      msgType,
+		Version:   ProtocolVersion,
+		AuctionID: auctionID,
+		Seq:       seq,
+	}
+	if payload != nil {
+		rawPayload, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		msg.Payload = rawPayload
+	}
+	return json.Marshal(&msg)
+}
+
+// reply sends a message to the client in response to req, echoing its correlation ID.
+// req may be nil when the inbound message could not be parsed.
+func (c *Client) reply(req *Message, msgType string, payload any) {
+	msg := Message{
+		Type:    msgType,
+		Version: c.Version,
+	}
+	if req != nil {
+		msg.ID = req.ID
+		msg.AuctionID = req.AuctionID
+	}
+	if payload != nil {
+		rawPayload, err := json.Marshal(payload)
+		if err != nil {
+			log.Errorf("Error marshalling %s payload: %v", msgType, err)
+			return
+		}
+		msg.Payload = rawPayload
+	}
+
+	rawMessage, err := json.Marshal(&msg)
+	if err != nil {
+		log.Errorf("Error marshalling %s message: %v", msgType, err)
+		return
+	}
+	c.enqueue(rawMessage, KindDirect, "")
+}
+
+// ack tells the client that req was processed. The payload depends on the type of req.
+func (c *Client) ack(req *Message, payload any) {
+	c.reply(req, TypeAck, payload)
+}
+
+// nack tells the client that req was rejected, with a machine-readable error code.
+func (c *Client) nack(req *Message, appErr *errors.AppError) {
+	c.reply(req, TypeNack, &NackPayload{Code: appErr.Code, Message: appErr.Message})
+}
+
+// negotiateVersion picks the protocol version requested by the client.
+// It returns false when the client only asks for versions the server does not speak.
+func negotiateVersion(r *http.Request) (int, bool) {
+	var requested []int
+	if query := r.URL.Query().Get("version"); query != "" {
+		version, err := strconv.Atoi(query)
+		if err != nil {
+			return 0, false
+		}
+		requested = append(requested, version)
+	}
+	for _, subprotocol := range websocket.Subprotocols(r) {
+		if !strings.HasPrefix(subprotocol, subprotocolPrefix) {
+			continue
+		}
+		if version, err := strconv.Atoi(strings.TrimPrefix(subprotocol, subprotocolPrefix)); err == nil {
+			requested = append(requested, version)
+		}
+	}
+
+	if len(requested) == 0 {
+		return ProtocolVersion, true
+	}
+	for _, version := range supportedVersions {
+		for _, candidate := range requested {
+			if candidate == version {
+				return version, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// subprotocolHeader returns the response header accepting the negotiated subprotocol,
+// or nil when the client did not ask for one.
+func subprotocolHeader(r *http.Request, version int) http.Header {
+	want := subprotocolPrefix + strconv.Itoa(version)
+	for _, protocol := range websocket.Subprotocols(r) {
+		if protocol == want {
+			return http.Header{"Sec-WebSocket-Protocol": {want}}
+		}
+	}
+	return nil
+}
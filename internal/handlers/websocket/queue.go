@@ -0,0 +1,297 @@
+package websocket
+
+import (
+	"sync"
+
+	"github.com/charmbracelet/log"
+)
+
+// QueuePolicy decides what happens when a client's send queue is full.
+// Whatever the policy, direct messages are never dropped: the queue grows past
+// its size for them, they are bounded by the client's own rate limit.
+type QueuePolicy string
+
+const (
+	// PolicyDisconnect closes the connection of a client that cannot keep up.
+	// The client reconnects and resumes from the last event it saw.
+	PolicyDisconnect QueuePolicy = "disconnect"
+	// PolicyDropOldest drops the oldest live message, or else the oldest auction
+	// event. Clients notice the gap in sequence numbers and resume.
+	PolicyDropOldest QueuePolicy = "drop_oldest"
+	// PolicyCoalesce drops the oldest live message, or else replaces the queued
+	// events of an auction with a single fresh snapshot of it, built when the
+	// snapshot is written. The connection is closed when nothing can be merged.
+	PolicyCoalesce QueuePolicy = "coalesce"
+)
+
+// MessageKind tells a full send queue which messages it may give up.
+type MessageKind int
+
+const (
+	// KindDirect messages are addressed to the client alone, such as replies to its
+	// messages and outbid notices. They are never dropped.
+	KindDirect MessageKind = iota
+	// KindEvent messages are sequenced auction events.
+	KindEvent
+	// KindLive messages, such as ticks and presence, only matter when sent.
+	// They are dropped first.
+	KindLive
+)
+
+// defaultSendQueueSize is the number of messages queued per client when not configured.
+const defaultSendQueueSize = 64
+
+// ParseQueuePolicy reads a policy from the configuration, defaulting to PolicyDisconnect.
+func ParseQueuePolicy(value string) QueuePolicy {
+	switch policy := QueuePolicy(value); policy {
+	case PolicyDisconnect, PolicyDropOldest, PolicyCoalesce:
+		return policy
+	case "":
+		return PolicyDisconnect
+	default:
+		log.Warnf("Unknown send queue policy %q, using %s", value, PolicyDisconnect)
+		return PolicyDisconnect
+	}
+}
+
+// queuedMessage is a message waiting to be written to a client.
+type queuedMessage struct {
+	data      []byte
+	kind      MessageKind
+	auctionID string // Set for auction events
+	snapshot  bool   // Stands for coalesced events, a snapshot of the auction is written instead
+}
+
+// SendQueue is the bounded outbound queue of a client. Pushing never blocks,
+// so neither broadcasts nor the client's own read loop wait on a slow socket.
+type SendQueue struct {
+	items   []queuedMessage
+	size    int
+	policy  QueuePolicy
+	ready   chan struct{} // Signalled when messages are queued or the queue is closed
+	closed  bool
+	dropped uint64 // Messages dropped since the queue was created
+	mu      sync.Mutex
+}
+
+// NewSendQueue creates a queue holding up to size messages.
+func NewSendQueue(size int, policy QueuePolicy) *SendQueue {
+	if size <= 0 {
+		size = defaultSendQueueSize
+	}
+	return &SendQueue{
+		items:  make([]queuedMessage, 0, size),
+		size:   size,
+		policy: policy,
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+// Push queues a message. auctionID is set for auction events.
+// It returns false when the message could not be queued because the queue is closed,
+// or is full and gets closed so the client is dropped. A live message given up
+// because the queue is full is counted as dropped, not reported.
+func (q *SendQueue) Push(data []byte, kind MessageKind, auctionID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+
+	// The snapshot is built when written, so it already covers the events queued after it
+	if kind == KindEvent && q.hasSnapshot(auctionID) {
+		q.dropped++
+		return true
+	}
+
+	if len(q.items) >= q.size && kind != KindDirect {
+		if q.policy == PolicyDisconnect {
+			q.dropped += uint64(len(q.items)) + 1
+			q.items = nil
+			q.closeLocked()
+			return false
+		}
+
+		// A coalesced event is covered by the snapshot standing for its auction
+		if kind == KindEvent && q.policy == PolicyCoalesce && q.coalesce(auctionID) {
+			q.dropped++
+			q.signal()
+			return true
+		}
+
+		if !q.makeRoom() {
+			q.dropped++
+			if kind == KindLive {
+				return true
+			}
+			q.dropped += uint64(len(q.items))
+			q.items = nil
+			q.closeLocked()
+			return false
+		}
+	}
+
+	q.items = append(q.items, queuedMessage{data: data, kind: kind, auctionID: auctionID})
+	q.signal()
+	return true
+}
+
+// makeRoom frees at least one slot according to the policy, without touching direct
+// messages. It returns false when no slot could be freed. The caller must hold q.mu.
+func (q *SendQueue) makeRoom() bool {
+	if q.removeFirst(func(item queuedMessage) bool { return item.kind == KindLive }) {
+		return true
+	}
+
+	if q.policy == PolicyDropOldest {
+		return q.removeFirst(func(item queuedMessage) bool { return item.kind == KindEvent })
+	}
+
+	// Merge the events of the first auction with several of them queued
+	queued := make(map[string]int)
+	for _, item := range q.items {
+		if item.kind == KindEvent {
+			queued[item.auctionID]++
+		}
+	}
+	for _, item := range q.items {
+		if item.kind == KindEvent && queued[item.auctionID] > 1 {
+			return q.coalesce(item.auctionID)
+		}
+	}
+	return false
+}
+
+// removeFirst drops the oldest message matching drop and reports whether one was found.
+// The caller must hold q.mu.
+func (q *SendQueue) removeFirst(drop func(queuedMessage) bool) bool {
+	for i, item := range q.items {
+		if drop(item) {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			q.dropped++
+			return true
+		}
+	}
+	return false
+}
+
+// coalesce replaces the queued events of an auction with a snapshot of it, in the place
+// of the first of them. It returns false when no event of the auction is queued.
+// The caller must hold q.mu.
+func (q *SendQueue) coalesce(auctionID string) bool {
+	first := -1
+	kept := q.items[:0]
+	for _, item := range q.items {
+		if item.kind != KindEvent || item.auctionID != auctionID {
+			kept = append(kept, item)
+			continue
+		}
+		if !item.snapshot {
+			q.dropped++
+		}
+		if first == -1 {
+			first = len(kept)
+			kept = append(kept, queuedMessage{kind: KindEvent, auctionID: auctionID, snapshot: true})
+		}
+	}
+	q.items = kept
+	return first != -1
+}
+
+// hasSnapshot reports whether a snapshot of the auction is queued. The caller must hold q.mu.
+func (q *SendQueue) hasSnapshot(auctionID string) bool {
+	for _, item := range q.items {
+		if item.snapshot && item.auctionID == auctionID {
+			return true
+		}
+	}
+	return false
+}
+
+// Pop removes the oldest queued message. When snapshotOf is set, the message stands for
+// coalesced events and a fresh snapshot of that auction must be written instead.
+// ok is false when the queue is empty or closed.
+func (q *SendQueue) Pop() (data []byte, snapshotOf string, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || len(q.items) == 0 {
+		return nil, "", false
+	}
+	item := q.items[0]
+	q.items = q.items[1:]
+	if item.snapshot {
+		return nil, item.auctionID, true
+	}
+	return item.data, "", true
+}
+
+// Ready returns a channel that receives when messages are queued or the queue is closed.
+func (q *SendQueue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+// Close discards the queued messages and wakes up the writer so it can exit.
+func (q *SendQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closeLocked()
+}
+
+// Closed reports whether the queue was closed.
+func (q *SendQueue) Closed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.closed
+}
+
+// Stats returns the number of queued and dropped messages.
+func (q *SendQueue) Stats() (queued int, dropped uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items), q.dropped
+}
+
+// closeLocked closes the queue. The caller must hold q.mu.
+func (q *SendQueue) closeLocked() {
+	if q.closed {
+		return
+	}
+	q.closed = true
+	q.items = nil
+	q.signal()
+}
+
+// signal wakes up the writer without blocking. The caller must hold q.mu.
+func (q *SendQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// QueueStats describes the send queue of a connected client.
+type QueueStats struct {
+	ClientID string `json:"client_id"`
+	Queued   int    `json:"queued"`
+	Dropped  uint64 `json:"dropped"`
+}
+
+// QueueStats returns the send queue statistics of every connected client, for monitoring.
+func (h *AuctionHandler) QueueStats() []QueueStats {
+	var stats []QueueStats
+	for _, client := range h.clients.All() {
+		queued, dropped := client.Send.Stats()
+		stats = append(stats, QueueStats{ClientID: client.ID, Queued: queued, Dropped: dropped})
+	}
+	return stats
+}
+
+// DroppedMessages returns the number of messages dropped by the send queues since the
+// server started, connected and disconnected clients alike.
+func (h *AuctionHandler) DroppedMessages() uint64 {
+	dropped := h.droppedMessages.Load()
+	for _, stats := range h.QueueStats() {
+		dropped += stats.Dropped
+	}
+	return dropped
+}
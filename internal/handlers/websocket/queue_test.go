@@ -0,0 +1,144 @@
+package websocket
+
+import (
+	"slices"
+	"testing"
+)
+
+// drain pops every queued message. Snapshots standing for coalesced events
+// are reported as "snapshot:" followed by the auction ID.
+func drain(q *SendQueue) []string {
+	var messages []string
+	for {
+		message, snapshotOf, ok := q.Pop()
+		if !ok {
+			return messages
+		}
+		if snapshotOf != "" {
+			messages = append(messages, "snapshot:"+snapshotOf)
+			continue
+		}
+		messages = append(messages, string(message))
+	}
+}
+
+// push describes a message pushed to a queue.
+type push struct {
+	data      string
+	kind      MessageKind
+	auctionID string
+}
+
+func TestSendQueuePolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  QueuePolicy
+		pushes  []push
+		want    []string
+		dropped uint64
+		closed  bool
+	}{
+		{
+			name:    "disconnect closes a full queue",
+			policy:  PolicyDisconnect,
+			pushes:  []push{{"a1", KindEvent, "a"}, {"b1", KindEvent, "b"}, {"a2", KindEvent, "a"}},
+			dropped: 3,
+			closed:  true,
+		},
+		{
+			name:    "drop oldest drops live messages first",
+			policy:  PolicyDropOldest,
+			pushes:  []push{{"a1", KindEvent, "a"}, {"tick", KindLive, "a"}, {"a2", KindEvent, "a"}},
+			want:    []string{"a1", "a2"},
+			dropped: 1,
+		},
+		{
+			name:    "drop oldest drops the oldest event",
+			policy:  PolicyDropOldest,
+			pushes:  []push{{"a1", KindEvent, "a"}, {"b1", KindEvent, "b"}, {"a2", KindEvent, "a"}},
+			want:    []string{"b1", "a2"},
+			dropped: 1,
+		},
+		{
+			name:    "live message dropped when only events are queued",
+			policy:  PolicyCoalesce,
+			pushes:  []push{{"a1", KindEvent, "a"}, {"b1", KindEvent, "b"}, {"tick", KindLive, "a"}},
+			want:    []string{"a1", "b1"},
+			dropped: 1,
+		},
+		{
+			name:    "coalesce replaces the events of the auction with a snapshot",
+			policy:  PolicyCoalesce,
+			pushes:  []push{{"a1", KindEvent, "a"}, {"b1", KindEvent, "b"}, {"a2", KindEvent, "a"}},
+			want:    []string{"snapshot:a", "b1"},
+			dropped: 2,
+		},
+		{
+			name:    "coalesce merges another auction to make room",
+			policy:  PolicyCoalesce,
+			pushes:  []push{{"a1", KindEvent, "a"}, {"a2", KindEvent, "a"}, {"b1", KindEvent, "b"}},
+			want:    []string{"snapshot:a", "b1"},
+			dropped: 2,
+		},
+		{
+			name:    "coalesce disconnects when nothing can be merged",
+			policy:  PolicyCoalesce,
+			pushes:  []push{{"a1", KindEvent, "a"}, {"b1", KindEvent, "b"}, {"c1", KindEvent, "c"}},
+			dropped: 3,
+			closed:  true,
+		},
+		{
+			name:   "replies are never dropped",
+			policy: PolicyDropOldest,
+			pushes: []push{{"ack1", KindDirect, ""}, {"ack2", KindDirect, ""}, {"ack3", KindDirect, ""}},
+			want:   []string{"ack1", "ack2", "ack3"},
+		},
+		{
+			name:    "events make room without dropping replies",
+			policy:  PolicyDropOldest,
+			pushes:  []push{{"ack1", KindDirect, ""}, {"a1", KindEvent, "a"}, {"a2", KindEvent, "a"}},
+			want:    []string{"ack1", "a2"},
+			dropped: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewSendQueue(2, tt.policy)
+			for _, p := range tt.pushes {
+				q.Push([]byte(p.data), p.kind, p.auctionID)
+			}
+
+			if got := drain(q); !slices.Equal(got, tt.want) {
+				t.Errorf("queued = %v, want %v", got, tt.want)
+			}
+			if _, dropped := q.Stats(); dropped != tt.dropped {
+				t.Errorf("dropped = %d, want %d", dropped, tt.dropped)
+			}
+			if q.Closed() != tt.closed {
+				t.Errorf("closed = %t, want %t", q.Closed(), tt.closed)
+			}
+		})
+	}
+}
+
+func TestSendQueueCoalesceKeepsOneSnapshot(t *testing.T) {
+	q := NewSendQueue(3, PolicyCoalesce)
+	q.Push([]byte("a1"), KindEvent, "a")
+	q.Push([]byte("ack"), KindDirect, "")
+	q.Push([]byte("a2"), KindEvent, "a")
+	q.Push([]byte("a3"), KindEvent, "a")
+	q.Push([]byte("a4"), KindEvent, "a")
+
+	if got, want := drain(q), []string{"snapshot:a", "ack"}; !slices.Equal(got, want) {
+		t.Errorf("queued = %v, want %v", got, want)
+	}
+}
+
+func TestSendQueueClosed(t *testing.T) {
+	q := NewSendQueue(2, PolicyDropOldest)
+	q.Close()
+	if q.Push([]byte("a1"), KindEvent, "a") {
+		t.Error("Push() on a closed queue = true, want false")
+	}
+}
@@ -0,0 +1,111 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/gorilla/websocket"
+)
+
+// defaultMaxConnectionsPerUser is the number of sockets a user may keep open when not configured.
+const defaultMaxConnectionsPerUser = 5
+
+// Registry indexes the connected clients by user, so that a user can be messaged
+// on every device and the number of sockets per user can be limited.
+type Registry struct {
+	users      map[string][]*Client // Connections of each user, oldest first
+	maxPerUser int
+	mu         sync.RWMutex
+}
+
+// NewRegistry creates an empty registry allowing up to maxPerUser connections per user.
+func NewRegistry(maxPerUser int) *Registry {
+	if maxPerUser <= 0 {
+		maxPerUser = defaultMaxConnectionsPerUser
+	}
+	return &Registry{
+		users:      make(map[string][]*Client),
+		maxPerUser: maxPerUser,
+	}
+}
+
+// Add registers a connection and returns the oldest connections of the same user
+// that no longer fit within the limit. The caller is responsible for closing them.
+func (r *Registry) Add(client *Client) []*Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	connections := append(r.users[client.ID], client)
+	var evicted []*Client
+	if excess := len(connections) - r.maxPerUser; excess > 0 {
+		evicted = append(evicted, connections[:excess]...)
+		connections = append([]*Client(nil), connections[excess:]...)
+	}
+	r.users[client.ID] = connections
+	return evicted
+}
+
+// Remove unregisters a connection. Removing an unknown connection does nothing.
+func (r *Registry) Remove(client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	connections := r.users[client.ID]
+	for i, connection := range connections {
+		if connection == client {
+			connections = append(connections[:i:i], connections[i+1:]...)
+			break
+		}
+	}
+	if len(connections) == 0 {
+		delete(r.users, client.ID)
+		return
+	}
+	r.users[client.ID] = connections
+}
+
+// Connections returns the live connections of a user.
+func (r *Registry) Connections(userID string) []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]*Client(nil), r.users[userID]...)
+}
+
+// All returns every registered connection.
+func (r *Registry) All() []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var clients []*Client
+	for _, connections := range r.users {
+		clients = append(clients, connections...)
+	}
+	return clients
+}
+
+// evict closes a connection pushed out by a newer one of the same user,
+// telling the client why before the socket goes away.
+func (h *AuctionHandler) evict(client *Client) {
+	log.Debugf("Client %s reached the connection limit, closing its oldest connection", client.ID)
+	closeMessage := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "Too many connections")
+	if err := client.Conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(h.heartbeat.WriteTimeout)); err != nil {
+		log.Debugf("Error sending close message to client %s: %v", client.ID, err)
+	}
+	client.Disconnect(h)
+}
+
+// SendToUser sends a message to every connection of a user and returns how many it reached.
+func (h *AuctionHandler) SendToUser(userID string, message []byte) int {
+	sent := 0
+	for _, client := range h.clients.Connections(userID) {
+		if h.sendTo(client, message, KindDirect, "") {
+			sent++
+		}
+	}
+	return sent
+}
+
+// IsOnline reports whether a user has at least one live connection.
+func (h *AuctionHandler) IsOnline(userID string) bool {
+	return len(h.clients.Connections(userID)) > 0
+}
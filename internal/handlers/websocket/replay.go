@@ -0,0 +1,155 @@
+package websocket
+
+import (
+	"sync"
+
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+	"github.com/charmbracelet/log"
+)
+
+// replayBufferSize is the number of recent events kept per auction for resuming clients.
+const replayBufferSize = 100
+
+// EventLog numbers the events of each auction and keeps the most recent ones
+// so that reconnecting clients can catch up on what they missed.
+type EventLog struct {
+	auctions map[string]*auctionEvents
+	size     int
+	mu       sync.Mutex
+}
+
+// auctionEvents holds the latest sequence number of an auction and its recent events,
+// the last one having sequence number seq.
+type auctionEvents struct {
+	seq    uint64
+	events [][]byte
+}
+
+// NewEventLog creates an event log keeping up to size events per auction.
+func NewEventLog(size int) *EventLog {
+	return &EventLog{
+		auctions: make(map[string]*auctionEvents),
+		size:     size,
+	}
+}
+
+// Seq returns the sequence number of the latest event of an auction, 0 if there is none.
+func (l *EventLog) Seq(auctionID string) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if auctionLog, exists := l.auctions[auctionID]; exists {
+		return auctionLog.seq
+	}
+	return 0
+}
+
+// Append assigns the next sequence number of an auction to an event, encodes it with encode
+// and keeps it for replay. deliver is called with the encoded event before the log is
+// unlocked, so events are delivered in sequence order.
+func (l *EventLog) Append(auctionID string, encode func(seq uint64) ([]byte, error), deliver func([]byte)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	auctionLog, exists := l.auctions[auctionID]
+	if !exists {
+		auctionLog = &auctionEvents{}
+		l.auctions[auctionID] = auctionLog
+	}
+
+	event, err := encode(auctionLog.seq + 1)
+	if err != nil {
+		return err
+	}
+	auctionLog.seq++
+	auctionLog.events = append(auctionLog.events, event)
+	if len(auctionLog.events) > l.size {
+		auctionLog.events = auctionLog.events[len(auctionLog.events)-l.size:]
+	}
+
+	deliver(event)
+	return nil
+}
+
+// Since returns the events of an auction that came after lastSeq, oldest first.
+// The boolean is false when some of them are no longer kept, or when lastSeq is
+// unknown to the log, in which case the client needs a fresh snapshot instead.
+func (l *EventLog) Since(auctionID string, lastSeq uint64) ([][]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	auctionLog, exists := l.auctions[auctionID]
+	if !exists {
+		return nil, lastSeq == 0
+	}
+	if lastSeq > auctionLog.seq {
+		return nil, false
+	}
+
+	missed := int(auctionLog.seq - lastSeq)
+	if missed > len(auctionLog.events) {
+		return nil, false
+	}
+	events := make([][]byte, missed)
+	copy(events, auctionLog.events[len(auctionLog.events)-missed:])
+	return events, true
+}
+
+// Forget drops the events of an auction.
+func (l *EventLog) Forget(auctionID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.auctions, auctionID)
+}
+
+// publish numbers an event of an auction, keeps it for replay and sends it to the auction's subscribers.
+func (h *AuctionHandler) publish(auction types.Auctions, msgType string, payload any) {
+	err := h.events.Append(auction.ID, func(seq uint64) ([]byte, error) {
+		return NewEvent(msgType, auction.ID, seq, payload)
+	}, func(event []byte) {
+		h.BroadcastAuction(auction, event)
+	})
+	if err != nil {
+		log.Errorf("Error marshalling %s event: %v", msgType, err)
+	}
+}
+
+// handleResumeMessage subscribes a reconnecting client to an auction again and
+// replays the events it missed since the last sequence number it saw.
+// When the missed events are no longer available, the ack carries a fresh snapshot.
+func (h *AuctionHandler) handleResumeMessage(client *Client, msg *Message) {
+	var resumeMsg ResumePayload
+	if err := msg.DecodePayload(&resumeMsg); err != nil || msg.AuctionID == "" {
+		client.nack(msg, errors.New(errors.ErrBadMessageFormat, "Invalid resume message"))
+		return
+	}
+
+	auction, appErr := h.visibleAuction(client, msg.AuctionID)
+	if appErr != nil {
+		client.nack(msg, appErr)
+		return
+	}
+
+	// Join before reading the log so no event falls between the two;
+	// events received twice are recognized by their sequence number.
+	h.rooms.Join(auction.ID, client)
+
+	events, ok := h.events.Since(auction.ID, resumeMsg.LastSeq)
+	if !ok {
+		log.Debugf("Client %s resumed auction %s too late, sending a snapshot", client.ID, auction.ID)
+		snapshot, err := h.buildSnapshot(client, auction)
+		if err != nil {
+			log.Error("Error building auction snapshot: ", err)
+			client.nack(msg, errors.Internal(err))
+			return
+		}
+		client.ack(msg, &ResumeAck{Snapshot: &snapshot})
+		return
+	}
+
+	for _, event := range events {
+		client.enqueue(event, KindEvent, auction.ID)
+	}
+	log.Debugf("Client %s resumed auction %s, %d events replayed", client.ID, auction.ID, len(events))
+	client.ack(msg, &ResumeAck{Replayed: len(events)})
+}
@@ -0,0 +1,156 @@
+package websocket
+
+import (
+	"sync"
+
+	"github.com/Martin-Hayot/auction-server/internal/bidding"
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+	"github.com/charmbracelet/log"
+)
+
+// Rooms tracks which clients are subscribed to which auctions.
+type Rooms struct {
+	members map[string]map[*Client]struct{} // Subscribed clients by auction ID
+	changed map[string]struct{}             // Auctions whose members changed since TakeChanged
+	mu      sync.RWMutex
+}
+
+// NewRooms creates an empty set of auction rooms.
+func NewRooms() *Rooms {
+	return &Rooms{
+		members: make(map[string]map[*Client]struct{}),
+		changed: make(map[string]struct{}),
+	}
+}
+
+// Join subscribes a client to an auction.
+func (r *Rooms) Join(auctionID string, client *Client) {
+	r.mu.Lock()
+	room, exists := r.members[auctionID]
+	if !exists {
+		room = make(map[*Client]struct{})
+		r.members[auctionID] = room
+	}
+	_, joined := room[client]
+	room[client] = struct{}{}
+	r.changed[auctionID] = struct{}{}
+	r.mu.Unlock()
+
+	if !joined {
+		client.mu.Lock()
+		client.Auctions = append(client.Auctions, auctionID)
+		client.mu.Unlock()
+	}
+}
+
+// Leave unsubscribes a client from an auction.
+func (r *Rooms) Leave(auctionID string, client *Client) {
+	r.mu.Lock()
+	r.remove(auctionID, client)
+	r.mu.Unlock()
+
+	client.mu.Lock()
+	for i, id := range client.Auctions {
+		if id == auctionID {
+			client.Auctions = append(client.Auctions[:i], client.Auctions[i+1:]...)
+			break
+		}
+	}
+	client.mu.Unlock()
+}
+
+// LeaveAll unsubscribes a client from every auction it joined.
+func (r *Rooms) LeaveAll(client *Client) {
+	client.mu.Lock()
+	auctionIDs := client.Auctions
+	client.Auctions = nil
+	client.mu.Unlock()
+
+	r.mu.Lock()
+	for _, auctionID := range auctionIDs {
+		r.remove(auctionID, client)
+	}
+	r.mu.Unlock()
+}
+
+// Members returns the clients subscribed to an auction.
+func (r *Rooms) Members(auctionID string) []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	clients := make([]*Client, 0, len(r.members[auctionID]))
+	for client := range r.members[auctionID] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// TakeChanged returns the auctions whose members changed since the last call.
+func (r *Rooms) TakeChanged() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	auctionIDs := make([]string, 0, len(r.changed))
+	for auctionID := range r.changed {
+		auctionIDs = append(auctionIDs, auctionID)
+	}
+	clear(r.changed)
+	return auctionIDs
+}
+
+// remove deletes a client from a room and drops the room once empty.
+// The caller must hold r.mu.
+func (r *Rooms) remove(auctionID string, client *Client) {
+	room, exists := r.members[auctionID]
+	if !exists {
+		return
+	}
+	delete(room, client)
+	r.changed[auctionID] = struct{}{}
+	if len(room) == 0 {
+		delete(r.members, auctionID)
+	}
+}
+
+// handleJoinMessage subscribes the client to the events of an auction it is allowed to see.
+func (h *AuctionHandler) handleJoinMessage(client *Client, msg *Message) {
+	if msg.AuctionID == "" {
+		client.nack(msg, errors.New(errors.ErrBadMessageFormat, "Invalid join message"))
+		return
+	}
+
+	auction, appErr := h.visibleAuction(client, msg.AuctionID)
+	if appErr != nil {
+		client.nack(msg, appErr)
+		return
+	}
+
+	h.rooms.Join(auction.ID, client)
+	log.Debugf("Client %s joined auction %s", client.ID, auction.ID)
+	h.sendSnapshot(client, msg, auction)
+}
+
+// handleLeaveMessage unsubscribes the client from the events of an auction.
+func (h *AuctionHandler) handleLeaveMessage(client *Client, msg *Message) {
+	if msg.AuctionID == "" {
+		client.nack(msg, errors.New(errors.ErrBadMessageFormat, "Invalid leave message"))
+		return
+	}
+
+	h.rooms.Leave(msg.AuctionID, client)
+	log.Debugf("Client %s left auction %s", client.ID, msg.AuctionID)
+	client.ack(msg, nil)
+}
+
+// visibleAuction loads an auction the client is allowed to see.
+func (h *AuctionHandler) visibleAuction(client *Client, auctionID string) (types.Auctions, *errors.AppError) {
+	auction, err := h.db.GetAuctionById(auctionID)
+	if err != nil {
+		log.Debugf("Client %s requested unknown auction %s: %v", client.ID, auctionID, err)
+		return types.Auctions{}, errors.New(errors.ErrAuctionNotFound, "Auction not found")
+	}
+
+	if appErr := bidding.CheckVisibility(auction, client.Role); appErr != nil {
+		return types.Auctions{}, appErr
+	}
+	return auction, nil
+}
@@ -0,0 +1,95 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/Martin-Hayot/auction-server/internal/bidding"
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+	"github.com/charmbracelet/log"
+)
+
+// snapshotBidsLimit is the number of recent bids included in a snapshot.
+const snapshotBidsLimit = 10
+
+// buildSnapshot assembles the snapshot of an auction as seen by a client.
+func (h *AuctionHandler) buildSnapshot(client *Client, auction types.Auctions) (AuctionSnapshot, error) {
+	// Read the sequence number first: events after it may already be reflected
+	// in the snapshot, but none can be missed by a client resuming from it.
+	seq := h.events.Seq(auction.ID)
+	bids, err := h.db.GetRecentBids(auction.ID, snapshotBidsLimit)
+	if err != nil {
+		return AuctionSnapshot{}, err
+	}
+
+	now := time.Now().UTC()
+	snapshot := AuctionSnapshot{
+		Seq:             seq,
+		Status:          auction.Status,
+		CurrentBid:      auction.CurrentBid,
+		MinimumBid:      bidding.MinimumBid(auction),
+		BiddersCount:    auction.BiddersCount,
+		ReserveMet:      auction.CurrentBidderID != nil && auction.CurrentBid >= auction.ReservePrice,
+		Leading:         auction.CurrentBidderID != nil && *auction.CurrentBidderID == client.ID,
+		EndDate:         auction.EndDate.UTC(),
+		ServerTime:      now,
+		TimeRemainingMs: max(auction.EndDate.Sub(now), 0).Milliseconds(),
+		RecentBids:      make([]RecentBid, 0, len(bids)),
+	}
+	for _, bid := range bids {
+		snapshot.RecentBids = append(snapshot.RecentBids, RecentBid{
+			Amount:   bid.Price,
+			PlacedAt: bid.CreatedAt.UTC(),
+			Mine:     bid.UserID == client.ID,
+		})
+	}
+	return snapshot, nil
+}
+
+// snapshotEvent builds a snapshot event of an auction, sent in place of coalesced events.
+// It returns false when the auction cannot be sent to the client anymore.
+func (h *AuctionHandler) snapshotEvent(client *Client, auctionID string) ([]byte, bool) {
+	auction, appErr := h.visibleAuction(client, auctionID)
+	if appErr != nil {
+		log.Debugf("Dropping snapshot of auction %s for client %s: %v", auctionID, client.ID, appErr)
+		return nil, false
+	}
+	snapshot, err := h.buildSnapshot(client, auction)
+	if err != nil {
+		log.Error("Error building auction snapshot: ", err)
+		return nil, false
+	}
+	rawMessage, err := NewEvent(TypeSnapshot, auction.ID, snapshot.Seq, &snapshot)
+	if err != nil {
+		log.Error("Error marshalling snapshot message: ", err)
+		return nil, false
+	}
+	return rawMessage, true
+}
+
+// sendSnapshot acknowledges req with the current state of an auction.
+func (h *AuctionHandler) sendSnapshot(client *Client, req *Message, auction types.Auctions) {
+	snapshot, err := h.buildSnapshot(client, auction)
+	if err != nil {
+		log.Error("Error building auction snapshot: ", err)
+		client.nack(req, errors.New(errors.ErrInternalServer, "Internal server error"))
+		return
+	}
+	client.ack(req, &snapshot)
+}
+
+// handleUpdateMessage replies with a snapshot of the requested auction.
+func (h *AuctionHandler) handleUpdateMessage(client *Client, msg *Message) {
+	if msg.AuctionID == "" {
+		client.nack(msg, errors.New(errors.ErrBadMessageFormat, "Invalid update message"))
+		return
+	}
+
+	auction, appErr := h.visibleAuction(client, msg.AuctionID)
+	if appErr != nil {
+		client.nack(msg, appErr)
+		return
+	}
+
+	h.sendSnapshot(client, msg, auction)
+}
@@ -0,0 +1,230 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Martin-Hayot/auction-server/internal/auth"
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+	"github.com/charmbracelet/log"
+)
+
+// maxStreamAuctions is the number of auctions a single event stream may follow.
+const maxStreamAuctions = 20
+
+// eventStream writes auction events to a Server-Sent Events response.
+// Its event IDs carry the last sequence number sent for every auction,
+// so that a reconnecting browser resumes all of them with Last-Event-ID.
+type eventStream struct {
+	w            http.ResponseWriter
+	rc           *http.ResponseController
+	writeTimeout time.Duration
+	lastSeq      map[string]uint64 // Last sequence number sent by auction ID
+}
+
+// send writes an encoded message as an event named after its type.
+// Events already sent, as told by their sequence number, are skipped.
+func (s *eventStream) send(rawMessage []byte) error {
+	msg, err := ParseMessage(rawMessage)
+	if err != nil {
+		return err
+	}
+	if msg.Seq != 0 && msg.AuctionID != "" {
+		if msg.Type != TypeSnapshot && msg.Seq <= s.lastSeq[msg.AuctionID] {
+			return nil
+		}
+		s.lastSeq[msg.AuctionID] = msg.Seq
+	}
+
+	s.rc.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+	_, err = fmt.Fprintf(s.w, "id: %s\nevent: %s\ndata: %s\n\n", formatEventID(s.lastSeq), msg.Type, rawMessage)
+	if err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// ping writes a comment to keep proxies from closing an idle stream.
+func (s *eventStream) ping() error {
+	s.rc.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// HandleAuctionEvents streams the events of one or more auctions over Server-Sent Events,
+// for spectators whose network blocks WebSocket upgrades. Auctions are given with the
+// "auction_id" query parameter, repeated or comma-separated. The stream is read-only:
+// signed-in users get the events they would get over the WebSocket, other visitors
+// watch anonymously and cannot follow merchant-only auctions.
+func (h *AuctionHandler) HandleAuctionEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	auctionIDs := streamAuctionIDs(r)
+	if len(auctionIDs) == 0 || len(auctionIDs) > maxStreamAuctions {
+		http.Error(w, errors.New(errors.ErrBadRequest,
+			fmt.Sprintf("Between 1 and %d auction IDs are required", maxStreamAuctions)).ToJSON(), http.StatusBadRequest)
+		return
+	}
+
+	client := &Client{
+		Anonymous: true,
+		Version:   ProtocolVersion,
+		Heartbeat: h.heartbeat,
+		Send:      NewSendQueue(h.queueSize, h.queuePolicy),
+	}
+	if _, err := r.Cookie(auth.SessionCookie); err == nil {
+		user, appErr := h.authenticate(r)
+		if appErr != nil {
+			http.Error(w, appErr.Message, http.StatusUnauthorized)
+			return
+		}
+		client.ID, client.Email, client.Role, client.Anonymous = user.ID, user.Email, user.Role, false
+	}
+
+	auctions := make([]types.Auctions, 0, len(auctionIDs))
+	for _, auctionID := range auctionIDs {
+		auction, appErr := h.visibleAuction(client, auctionID)
+		if appErr != nil {
+			http.Error(w, appErr.ToJSON(), appErr.HTTPStatus())
+			return
+		}
+		auctions = append(auctions, auction)
+	}
+
+	stream := &eventStream{
+		w:            w,
+		rc:           http.NewResponseController(w),
+		writeTimeout: h.heartbeat.WriteTimeout,
+		lastSeq:      make(map[string]uint64),
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep reverse proxies from buffering the stream
+	w.WriteHeader(http.StatusOK)
+
+	defer client.Disconnect(h)
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	resume := parseEventID(lastEventID)
+	for _, auction := range auctions {
+		// Join before reading the log so no event falls between the two,
+		// the stream skips the events it already sent
+		h.rooms.Join(auction.ID, client)
+		if err := h.catchUp(stream, client, auction, resume); err != nil {
+			log.Debugf("Error starting event stream for %s: %v", r.RemoteAddr, err)
+			return
+		}
+	}
+	log.Debugf("Event stream opened for %s on %d auctions", r.RemoteAddr, len(auctions))
+
+	ticker := time.NewTicker(h.heartbeat.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Debugf("Event stream closed for %s", r.RemoteAddr)
+			return
+		case <-client.Send.Ready():
+			for {
+				message, snapshotOf, ok := client.Send.Pop()
+				if !ok {
+					break
+				}
+				if snapshotOf != "" {
+					if message, ok = h.snapshotEvent(client, snapshotOf); !ok {
+						continue
+					}
+				}
+				if err := stream.send(message); err != nil {
+					log.Debugf("Error streaming to %s: %v", r.RemoteAddr, err)
+					return
+				}
+			}
+			if client.Send.Closed() {
+				return
+			}
+		case <-ticker.C:
+			if err := stream.ping(); err != nil {
+				log.Debugf("Error pinging event stream of %s: %v", r.RemoteAddr, err)
+				return
+			}
+		}
+	}
+}
+
+// catchUp sends the events of an auction missed since the last event ID, or a snapshot
+// when the stream is new or the missed events are no longer available.
+func (h *AuctionHandler) catchUp(stream *eventStream, client *Client, auction types.Auctions, resume map[string]uint64) error {
+	if lastSeq, ok := resume[auction.ID]; ok {
+		if events, ok := h.events.Since(auction.ID, lastSeq); ok {
+			stream.lastSeq[auction.ID] = lastSeq
+			for _, event := range events {
+				if err := stream.send(event); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	snapshot, err := h.buildSnapshot(client, auction)
+	if err != nil {
+		return err
+	}
+	rawMessage, err := NewEvent(TypeSnapshot, auction.ID, snapshot.Seq, &snapshot)
+	if err != nil {
+		return err
+	}
+	return stream.send(rawMessage)
+}
+
+// streamAuctionIDs reads the auction IDs of an event stream request, without duplicates.
+func streamAuctionIDs(r *http.Request) []string {
+	var auctionIDs []string
+	for _, value := range r.URL.Query()["auction_id"] {
+		for _, auctionID := range strings.Split(value, ",") {
+			auctionID = strings.TrimSpace(auctionID)
+			if auctionID != "" && !slices.Contains(auctionIDs, auctionID) {
+				auctionIDs = append(auctionIDs, auctionID)
+			}
+		}
+	}
+	return auctionIDs
+}
+
+// formatEventID encodes the last sequence number of every auction as "id:seq,id:seq".
+func formatEventID(lastSeq map[string]uint64) string {
+	parts := make([]string, 0, len(lastSeq))
+	for auctionID, seq := range lastSeq {
+		parts = append(parts, auctionID+":"+strconv.FormatUint(seq, 10))
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ",")
+}
+
+// parseEventID decodes an event ID written by formatEventID, ignoring malformed parts.
+func parseEventID(eventID string) map[string]uint64 {
+	lastSeq := make(map[string]uint64)
+	for _, part := range strings.Split(eventID, ",") {
+		auctionID, rawSeq, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || auctionID == "" {
+			continue
+		}
+		if seq, err := strconv.ParseUint(rawSeq, 10, 64); err == nil {
+			lastSeq[auctionID] = seq
+		}
+	}
+	return lastSeq
+}
@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+	"github.com/charmbracelet/log"
+)
+
+// tickWindow is how long before the end of an auction tick events start.
+const tickWindow = time.Minute
+
+// handleTimeSyncMessage answers a "time_sync" request with the server timestamps of
+// the round trip, from which the client computes its clock offset and latency:
+//
+//	offset = ((server_received_at - client_sent_at) + (server_sent_at - client_received_at)) / 2
+//	delay  = (client_received_at - client_sent_at) - (server_sent_at - server_received_at)
+func (h *AuctionHandler) handleTimeSyncMessage(client *Client, msg *Message, receivedAt time.Time) {
+	var syncMsg TimeSyncPayload
+	if err := msg.DecodePayload(&syncMsg); err != nil {
+		client.nack(msg, errors.New(errors.ErrBadMessageFormat, "Invalid time_sync message"))
+		return
+	}
+
+	client.ack(msg, &TimeSyncAck{
+		ClientSentAt:     syncMsg.ClientSentAt,
+		ServerReceivedAt: receivedAt.UnixMilli(),
+		ServerSentAt:     time.Now().UnixMilli(),
+	})
+}
+
+// broadcastTicks sends a tick event to the subscribers of every auction in its final minute,
+// carrying the end date the server enforces.
+func (h *AuctionHandler) broadcastTicks() {
+	now := time.Now().UTC()
+
+	h.jobsMutex.RLock()
+	var ending []types.Auctions
+	for _, job := range h.activeJobs {
+		remaining := job.auctionEndDateUTC.Sub(now)
+		if job.timer == nil || remaining <= 0 || remaining > tickWindow {
+			continue
+		}
+		ending = append(ending, types.Auctions{
+			ID:               job.auctionID,
+			EndDate:          job.auctionEndDateUTC,
+			OnlyForMerchants: job.onlyForMerchants,
+		})
+	}
+	h.jobsMutex.RUnlock()
+
+	for _, auction := range ending {
+		// Ticks are not worth replaying, they are sent without a sequence number and
+		// queued as live messages, so a full queue never merges them with auction events
+		rawMessage, err := NewEvent(TypeTick, auction.ID, 0, &TickEvent{
+			EndDate:         auction.EndDate,
+			ServerTime:      now,
+			TimeRemainingMs: auction.EndDate.Sub(now).Milliseconds(),
+		})
+		if err != nil {
+			log.Error("Error marshalling tick message: ", err)
+			continue
+		}
+		h.broadcastRoom(auction, rawMessage, KindLive, "")
+	}
+}
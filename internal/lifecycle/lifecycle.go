@@ -0,0 +1,113 @@
+package lifecycle
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+)
+
+// transitions lists, for each status, the statuses an auction may move to.
+// Statuses without an entry allow no transition.
+var transitions = map[types.AuctionStatus][]types.AuctionStatus{
+	types.StatusDraft:     {types.StatusScheduled, types.StatusCancelled},
+	types.StatusScheduled: {types.StatusLive, types.StatusDraft, types.StatusCancelled},
+	types.StatusLive: {
+		types.StatusPaused,
+		types.StatusEnded,
+		types.StatusSold,
+		types.StatusReserveNotMet,
+		types.StatusCancelled,
+	},
+	types.StatusPaused: {
+		types.StatusLive,
+		types.StatusEnded,
+		types.StatusSold,
+		types.StatusReserveNotMet,
+		types.StatusCancelled,
+	},
+}
+
+// CanTransition reports whether an auction may move from one status to another.
+func CanTransition(from, to types.AuctionStatus) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal reports whether the status is one an auction closes with.
+// Unknown statuses are not final, such auctions are neither closed nor open for bids.
+func IsFinal(status types.AuctionStatus) bool {
+	switch status {
+	case types.StatusEnded, types.StatusSold, types.StatusReserveNotMet, types.StatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Transition moves the auction to a new status if the transition table allows it.
+// The auction is left untouched when the transition is rejected.
+func Transition(auction *types.Auctions, to types.AuctionStatus) *errors.AppError {
+	if !CanTransition(auction.Status, to) {
+		return errors.New(errors.ErrInvalidTransition,
+			fmt.Sprintf("Auction cannot go from %s to %s", auction.Status, to))
+	}
+	auction.Status = to
+	return nil
+}
+
+// Outcome returns the status a live auction closes with when its time runs out.
+func Outcome(auction types.Auctions) types.AuctionStatus {
+	switch {
+	case auction.CurrentBidderID == nil:
+		return types.StatusEnded
+	case auction.CurrentBid < auction.ReservePrice:
+		return types.StatusReserveNotMet
+	default:
+		return types.StatusSold
+	}
+}
+
+// AcceptsBids checks that the auction is open for bidding at the given time.
+// It returns nil when bids may be placed.
+func AcceptsBids(auction types.Auctions, now time.Time) *errors.AppError {
+	if IsFinal(auction.Status) || !now.Before(auction.EndDate) {
+		return errors.New(errors.ErrAuctionClosed, "Auction is closed")
+	}
+	if auction.Status == types.StatusDraft || auction.Status == types.StatusScheduled || now.Before(auction.StartDate) {
+		return errors.New(errors.ErrAuctionNotStarted, "Auction has not started yet")
+	}
+	if auction.Status != types.StatusLive {
+		return errors.New(errors.ErrAuctionNotLive, "Auction is not accepting bids")
+	}
+	return nil
+}
+
+// OpenIfDue moves a scheduled auction to live when its start date has passed,
+// so bids are not rejected while waiting for the periodic check.
+// It reports whether the status changed.
+func OpenIfDue(auction *types.Auctions, now time.Time) bool {
+	if auction.Status != types.StatusScheduled || now.Before(auction.StartDate) || !now.Before(auction.EndDate) {
+		return false
+	}
+	return Transition(auction, types.StatusLive) == nil
+}
+
+// BidderOutcome returns how the auction turned out for one of its bidders.
+func BidderOutcome(auction types.Auctions, userID string) types.BidderOutcome {
+	switch {
+	case !IsFinal(auction.Status):
+		return types.OutcomePending
+	case auction.Status == types.StatusCancelled:
+		return types.OutcomeCancelled
+	case auction.Status == types.StatusSold && auction.WinnerID != nil && *auction.WinnerID == userID:
+		return types.OutcomeWon
+	default:
+		return types.OutcomeLost
+	}
+}
@@ -0,0 +1,96 @@
+package lifecycle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Martin-Hayot/auction-server/pkg/errors"
+	"github.com/Martin-Hayot/auction-server/pkg/types"
+)
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		from, to types.AuctionStatus
+		allowed  bool
+	}{
+		{from: types.StatusScheduled, to: types.StatusLive, allowed: true},
+		{from: types.StatusLive, to: types.StatusSold, allowed: true},
+		{from: types.StatusPaused, to: types.StatusLive, allowed: true},
+		{from: types.StatusDraft, to: types.StatusLive},
+		{from: types.StatusSold, to: types.StatusLive},
+		{from: types.StatusLive, to: types.StatusLive},
+		{from: "active", to: types.StatusLive},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			auction := types.Auctions{Status: tt.from}
+			appErr := Transition(&auction, tt.to)
+			if (appErr == nil) != tt.allowed {
+				t.Fatalf("Transition() = %v, want allowed %t", appErr, tt.allowed)
+			}
+			want := tt.from
+			if tt.allowed {
+				want = tt.to
+			}
+			if auction.Status != want {
+				t.Errorf("status = %s, want %s", auction.Status, want)
+			}
+		})
+	}
+}
+
+func TestIsFinal(t *testing.T) {
+	tests := []struct {
+		status types.AuctionStatus
+		want   bool
+	}{
+		{status: types.StatusLive},
+		{status: types.StatusPaused},
+		{status: types.StatusEnded, want: true},
+		{status: types.StatusSold, want: true},
+		{status: types.StatusReserveNotMet, want: true},
+		{status: types.StatusCancelled, want: true},
+		{status: "active"}, // Unknown statuses are not closed
+	}
+
+	for _, tt := range tests {
+		if got := IsFinal(tt.status); got != tt.want {
+			t.Errorf("IsFinal(%q) = %t, want %t", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestAcceptsBids(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	open := func(status types.AuctionStatus) types.Auctions {
+		return types.Auctions{Status: status, StartDate: now.Add(-time.Hour), EndDate: now.Add(time.Hour)}
+	}
+	ended := open(types.StatusLive)
+	ended.EndDate = now
+
+	tests := []struct {
+		name    string
+		auction types.Auctions
+		want    int // Expected error code, 0 when bids are accepted
+	}{
+		{name: "live", auction: open(types.StatusLive)},
+		{name: "past the end date", auction: ended, want: errors.ErrAuctionClosed},
+		{name: "sold", auction: open(types.StatusSold), want: errors.ErrAuctionClosed},
+		{name: "scheduled", auction: open(types.StatusScheduled), want: errors.ErrAuctionNotStarted},
+		{name: "paused", auction: open(types.StatusPaused), want: errors.ErrAuctionNotLive},
+		{name: "unknown status", auction: open("active"), want: errors.ErrAuctionNotLive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appErr := AcceptsBids(tt.auction, now)
+			switch {
+			case tt.want == 0 && appErr != nil:
+				t.Errorf("AcceptsBids() = %v, want nil", appErr)
+			case tt.want != 0 && (appErr == nil || appErr.Code != tt.want):
+				t.Errorf("AcceptsBids() = %v, want code %d", appErr, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+)
+
+// Outbid describes a user losing the lead on an auction.
+type Outbid struct {
+	UserID    string
+	AuctionID string
+	Price     int // Current price of the auction after the new bid
+}
+
+// Notifier delivers events to users through an out-of-band channel, such as
+// email or push notifications, when they have no live connection.
+type Notifier interface {
+	NotifyOutbid(ctx context.Context, event Outbid) error
+}
+
+// LogNotifier only logs the notifications. It is used until a real backend is plugged in.
+type LogNotifier struct{}
+
+// NotifyOutbid logs the outbid event.
+func (LogNotifier) NotifyOutbid(ctx context.Context, event Outbid) error {
+	log.Infof("User %s was outbid on auction %s at %d", event.UserID, event.AuctionID, event.Price)
+	return nil
+}
@@ -1,6 +1,10 @@
 package errors
 
-import "fmt"
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+)
 
 type AppError struct {
 	Code    int    // HTTP status code or custom error code
@@ -9,13 +13,23 @@ type AppError struct {
 }
 
 const (
-	ErrInvalidToken       = 1001
-	ErrAuctionNotFound    = 1002
-	ErrBidTooLow          = 1003
-	ErrAuctionClosed      = 1004
-	ErrWebSocketUpgrade   = 1005
-	ErrBadMessageFormat   = 1006
-	ErrUnknownMessageType = 1007
+	ErrInvalidToken         = 1001
+	ErrAuctionNotFound      = 1002
+	ErrBidTooLow            = 1003
+	ErrAuctionClosed        = 1004
+	ErrWebSocketUpgrade     = 1005
+	ErrBadMessageFormat     = 1006
+	ErrUnknownMessageType   = 1007
+	ErrBidIncrementTooSmall = 1008
+	ErrBidBelowStartPrice   = 1009
+	ErrBidAboveMaxPrice     = 1010
+	ErrAuctionNotLive       = 1011
+	ErrInvalidTransition    = 1012
+	ErrAuctionNotStarted    = 1013
+	ErrMerchantOnly         = 1014
+	ErrRateLimited          = 1015
+	ErrUnsupportedVersion   = 1016
+	ErrIdempotencyKeyReused = 1017
 
 	ErrBadRequest     = 400
 	ErrInternalServer = 500
@@ -28,8 +42,44 @@ func (e *AppError) Error() string {
 	return e.Message
 }
 
+// Unwrap returns the underlying error.
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// ToJSON encodes the code and message of the error as a JSON object.
+// The message is escaped, it may quote user input.
 func (e *AppError) ToJSON() string {
-	return fmt.Sprintf(`{"code": %d, "message": "%s"}`, e.Code, e.Message)
+	message, err := json.Marshal(e.Message)
+	if err != nil {
+		message = []byte(`""`)
+	}
+	return fmt.Sprintf(`{"code": %d, "message": %s}`, e.Code, message)
+}
+
+// HTTPStatus returns the HTTP status code matching the error code.
+// Codes that already are HTTP status codes are returned as is.
+func (e *AppError) HTTPStatus() int {
+	switch e.Code {
+	case ErrInvalidToken:
+		return 401
+	case ErrMerchantOnly:
+		return 403
+	case ErrAuctionNotFound:
+		return 404
+	case ErrAuctionClosed, ErrAuctionNotLive, ErrAuctionNotStarted, ErrInvalidTransition, ErrIdempotencyKeyReused:
+		return 409
+	case ErrBidTooLow, ErrBidIncrementTooSmall, ErrBidBelowStartPrice, ErrBidAboveMaxPrice:
+		return 422
+	case ErrRateLimited:
+		return 429
+	case ErrBadMessageFormat, ErrUnknownMessageType, ErrUnsupportedVersion:
+		return 400
+	}
+	if e.Code >= 400 && e.Code < 600 {
+		return e.Code
+	}
+	return 500
 }
 
 // Wrapping utility
@@ -41,3 +91,18 @@ func Wrap(err error, message string) *AppError {
 func New(code int, message string) *AppError {
 	return &AppError{Code: code, Message: message}
 }
+
+// Internal wraps an unexpected error behind a generic user-facing message.
+func Internal(err error) *AppError {
+	return &AppError{Code: ErrInternalServer, Message: "Internal server error", Err: err}
+}
+
+// Is reports whether any error in err's chain matches target.
+func Is(err, target error) bool {
+	return stderrors.Is(err, target)
+}
+
+// As finds the first error in err's chain that matches target.
+func As(err error, target any) bool {
+	return stderrors.As(err, target)
+}
@@ -4,6 +4,9 @@ import (
 	"time"
 )
 
+// RoleMerchant is the role of users trading on behalf of a dealership.
+const RoleMerchant = "MERCHANT"
+
 type User struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
@@ -12,41 +15,84 @@ type User struct {
 	Role     string `json:"role"`
 }
 
+// AuctionStatus is the lifecycle state of an auction.
+type AuctionStatus string
+
+const (
+	StatusDraft         AuctionStatus = "draft"
+	StatusScheduled     AuctionStatus = "scheduled"
+	StatusLive          AuctionStatus = "live"
+	StatusPaused        AuctionStatus = "paused"
+	StatusEnded         AuctionStatus = "ended" // Closed without any bid
+	StatusSold          AuctionStatus = "sold"
+	StatusReserveNotMet AuctionStatus = "reserve_not_met"
+	StatusCancelled     AuctionStatus = "cancelled"
+)
+
 type Auctions struct {
-	ID               string    `json:"id"`
-	Mileage          int       `json:"mileage"`
-	State            string    `json:"state"`
-	CirculationDate  time.Time `json:"circulationDate"`
-	FuelType         string    `json:"fuelType"`
-	Power            int       `json:"power"`
-	Transmission     string    `json:"transmission"`
-	CarBody          string    `json:"carBody"`
-	GearBox          string    `json:"gearBox"`
-	Color            string    `json:"color"`
-	Doors            int       `json:"doors"`
-	Seats            int       `json:"seats"`
-	StartDate        time.Time `json:"startDate"`
-	EndDate          time.Time `json:"endDate"`
-	StartPrice       int       `json:"startPrice"`
-	MaxPrice         int       `json:"maxPrice"`
-	ReservePrice     int       `json:"reservePrice"`
-	CurrentBid       int       `json:"currentBid"`
-	BidIncrement     int       `json:"bidIncrement"`
-	CurrentBidderID  *string   `json:"currentBidderId,omitempty"`
-	BiddersCount     int       `json:"biddersCount"`
-	WinnerID         *string   `json:"winnerId,omitempty"`
-	OnlyForMerchants bool      `json:"onlyForMerchants"`
-	Status           string    `json:"status"`
-	CarID            string    `json:"carId"`
-	CreatedAt        time.Time `json:"createdAt"`
-	UpdatedAt        time.Time `json:"updatedAt"`
+	ID               string        `json:"id"`
+	Mileage          int           `json:"mileage"`
+	State            string        `json:"state"`
+	CirculationDate  time.Time     `json:"circulationDate"`
+	FuelType         string        `json:"fuelType"`
+	Power            int           `json:"power"`
+	Transmission     string        `json:"transmission"`
+	CarBody          string        `json:"carBody"`
+	GearBox          string        `json:"gearBox"`
+	Color            string        `json:"color"`
+	Doors            int           `json:"doors"`
+	Seats            int           `json:"seats"`
+	StartDate        time.Time     `json:"startDate"`
+	EndDate          time.Time     `json:"endDate"`
+	OriginalEndDate  *time.Time    `json:"originalEndDate,omitempty"`
+	StartPrice       int           `json:"startPrice"`
+	MaxPrice         int           `json:"maxPrice"`
+	ReservePrice     int           `json:"reservePrice,omitempty"`
+	CurrentBid       int           `json:"currentBid"`
+	BidIncrement     int           `json:"bidIncrement"`
+	CurrentBidderID  *string       `json:"currentBidderId,omitempty"`
+	BiddersCount     int           `json:"biddersCount"`
+	WinnerID         *string       `json:"winnerId,omitempty"`
+	OnlyForMerchants bool          `json:"onlyForMerchants"`
+	Status           AuctionStatus `json:"status"`
+	CarID            string        `json:"carId"`
+	CreatedAt        time.Time     `json:"createdAt"`
+	UpdatedAt        time.Time     `json:"updatedAt"`
 }
 
 type Bid struct {
+	ID             string    `json:"id"`
+	AuctionID      string    `json:"auctionId"`
+	UserID         string    `json:"userId"`
+	Price          int       `json:"price"`
+	IdempotencyKey *string   `json:"idempotencyKey,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+type ProxyBid struct {
 	ID        string    `json:"id"`
 	AuctionID string    `json:"auctionId"`
 	UserID    string    `json:"userId"`
-	Price     int       `json:"price"`
+	MaxAmount int       `json:"maxAmount"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
+
+// BidderOutcome is how an auction turned out for one of its bidders.
+type BidderOutcome string
+
+const (
+	OutcomePending   BidderOutcome = "pending" // The auction is not closed yet
+	OutcomeWon       BidderOutcome = "won"
+	OutcomeLost      BidderOutcome = "lost"
+	OutcomeCancelled BidderOutcome = "cancelled"
+)
+
+// ClientAuction is an auction a user has bid on, seen from that user.
+type ClientAuction struct {
+	Auction    Auctions      `json:"auction"`
+	HighestBid int           `json:"highestBid"` // Highest bid of the user
+	Winning    bool          `json:"winning"`    // The user holds the current bid
+	Outcome    BidderOutcome `json:"outcome"`
+}